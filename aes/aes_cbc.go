@@ -4,12 +4,21 @@ import (
 	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
-	"crypto/rand"
+	"errors"
 	"fmt"
 	"io"
+
+	"github.com/jessesomerville/cryptopals_set5/randutil"
 )
 
-func Encrypt(pt, key []byte) ([]byte, error) {
+// ErrInvalidPadding is returned by Decrypt when the plaintext does not end in
+// valid PKCS#7 padding.
+var ErrInvalidPadding = errors.New("invalid PKCS#7 padding")
+
+// Encrypt pads pt and encrypts it with AES-CBC under a random IV read from
+// random, or from crypto/rand if random is nil. The IV is prepended to the
+// ciphertext.
+func Encrypt(random io.Reader, pt, key []byte) ([]byte, error) {
 	padded := pkcs5(pt, aes.BlockSize)
 
 	block, err := aes.NewCipher(key)
@@ -19,7 +28,7 @@ func Encrypt(pt, key []byte) ([]byte, error) {
 
 	ct := make([]byte, aes.BlockSize+len(padded))
 	iv := ct[:aes.BlockSize]
-	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+	if err := randutil.Read(random, iv); err != nil {
 		return nil, err
 	}
 
@@ -44,9 +53,10 @@ func Decrypt(ct, key []byte) ([]byte, error) {
 		return nil, fmt.Errorf("ciphertext of length %d is not multiple of block size", len(ct))
 	}
 
+	pt := make([]byte, len(ct))
 	mode := cipher.NewCBCDecrypter(block, iv)
-	mode.CryptBlocks(ct, ct)
-	return removePadding(ct), nil
+	mode.CryptBlocks(pt, ct)
+	return removePadding(pt, aes.BlockSize)
 }
 
 func pkcs5(data []byte, blocksize int) []byte {
@@ -58,7 +68,21 @@ func pkcs5(data []byte, blocksize int) []byte {
 	return append(data, padding...)
 }
 
-func removePadding(data []byte) []byte {
-	padByte := data[len(data)-1]
-	return data[:len(data)-int(padByte)]
+// removePadding strips PKCS#7 padding, checking that the padding length is
+// between 1 and blocksize and that every padding byte holds that length.
+func removePadding(data []byte, blocksize int) ([]byte, error) {
+	if len(data) == 0 || len(data)%blocksize != 0 {
+		return nil, ErrInvalidPadding
+	}
+
+	padLen := int(data[len(data)-1])
+	if padLen == 0 || padLen > blocksize {
+		return nil, ErrInvalidPadding
+	}
+	for _, b := range data[len(data)-padLen:] {
+		if int(b) != padLen {
+			return nil, ErrInvalidPadding
+		}
+	}
+	return data[:len(data)-padLen], nil
 }
@@ -0,0 +1,69 @@
+package aescbc
+
+import (
+	"crypto/aes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrAuthentication is returned by DecryptAuthenticated when the MAC does not
+// match the IV and ciphertext.
+var ErrAuthentication = errors.New("message authentication failed")
+
+// DeriveKeys splits one session key into an AES-128 encryption key and an
+// HMAC-SHA256 key, so the same key is never used for both.
+func DeriveKeys(sessionKey []byte) (encKey, macKey []byte) {
+	encKey = hmacSHA256(sessionKey, []byte("aescbc encryption key"))[:16]
+	macKey = hmacSHA256(sessionKey, []byte("aescbc mac key"))
+	return encKey, macKey
+}
+
+// EncryptAuthenticated encrypts pt with AES-CBC and appends an HMAC-SHA256 tag
+// computed over the IV and ciphertext (encrypt-then-MAC). The IV is read from
+// random, or from crypto/rand if random is nil.
+func EncryptAuthenticated(random io.Reader, pt, sessionKey []byte) ([]byte, error) {
+	encKey, macKey := DeriveKeys(sessionKey)
+	return EncryptThenMAC(random, pt, encKey, macKey)
+}
+
+// DecryptAuthenticated checks the tag in constant time and only decrypts if it
+// matches, so an attacker never learns anything about the padding of a forged
+// ciphertext.
+func DecryptAuthenticated(ct, sessionKey []byte) ([]byte, error) {
+	encKey, macKey := DeriveKeys(sessionKey)
+	return OpenEncryptThenMAC(ct, encKey, macKey)
+}
+
+// EncryptThenMAC is EncryptAuthenticated with separately derived encryption
+// and MAC keys.
+func EncryptThenMAC(random io.Reader, pt, encKey, macKey []byte) ([]byte, error) {
+	ct, err := Encrypt(random, pt, encKey)
+	if err != nil {
+		return nil, err
+	}
+	return append(ct, hmacSHA256(macKey, ct)...), nil
+}
+
+// OpenEncryptThenMAC opens a ciphertext from EncryptThenMAC. It is
+// DecryptAuthenticated with separately derived encryption and MAC keys.
+func OpenEncryptThenMAC(ct, encKey, macKey []byte) ([]byte, error) {
+	if len(ct) < aes.BlockSize+sha256.Size {
+		return nil, fmt.Errorf("ciphertext of length %d is too short", len(ct))
+	}
+
+	tag := ct[len(ct)-sha256.Size:]
+	ct = ct[:len(ct)-sha256.Size]
+	if !hmac.Equal(tag, hmacSHA256(macKey, ct)) {
+		return nil, ErrAuthentication
+	}
+	return Decrypt(ct, encKey)
+}
+
+func hmacSHA256(key, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
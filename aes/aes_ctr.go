@@ -0,0 +1,167 @@
+package aescbc
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// CounterLayout selects how the nonce and block counter are packed into the
+// 16-byte counter block.
+type CounterLayout int
+
+const (
+	// LittleEndian64 is the cryptopals format: an 8-byte nonce followed by
+	// the block count as a 64-bit little-endian integer.
+	LittleEndian64 CounterLayout = iota
+	// BigEndian128 is the SP 800-38A format: a 16-byte initial counter block
+	// incremented as a 128-bit big-endian integer.
+	BigEndian128
+)
+
+// NonceSize returns the nonce length the layout expects.
+func (l CounterLayout) NonceSize() int {
+	if l == BigEndian128 {
+		return aes.BlockSize
+	}
+	return 8
+}
+
+// CTR generates an AES-CTR keystream that can be read from any offset. It
+// implements cipher.Stream, advancing its position with each call to
+// XORKeyStream, and io.Seeker to move that position.
+type CTR struct {
+	block  cipher.Block
+	nonce  []byte
+	layout CounterLayout
+
+	offset int64
+	// Keystream block currently cached and its index, or -1 if none
+	cached      []byte
+	cachedIndex int64
+}
+
+// NewCTR creates a CTR keystream for the key and nonce, positioned at offset 0.
+func NewCTR(key, nonce []byte, layout CounterLayout) (*CTR, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != layout.NonceSize() {
+		return nil, fmt.Errorf("nonce of length %d does not match counter layout, need %d", len(nonce), layout.NonceSize())
+	}
+
+	return &CTR{
+		block:       block,
+		nonce:       append([]byte{}, nonce...),
+		layout:      layout,
+		cached:      make([]byte, aes.BlockSize),
+		cachedIndex: -1,
+	}, nil
+}
+
+// XORKeyStream XORs src with the keystream at the current position into dst
+// and advances the position by len(src).
+func (c *CTR) XORKeyStream(dst, src []byte) {
+	c.XORKeyStreamAt(dst, src, c.offset)
+	c.offset += int64(len(src))
+}
+
+// XORKeyStreamAt XORs src with the keystream starting at offset into dst. It
+// does not change the current position.
+func (c *CTR) XORKeyStreamAt(dst, src []byte, offset int64) {
+	if len(dst) < len(src) {
+		panic("aescbc: output smaller than input")
+	}
+	for i := range src {
+		pos := offset + int64(i)
+		dst[i] = src[i] ^ c.keystreamBlock(pos / aes.BlockSize)[pos%aes.BlockSize]
+	}
+}
+
+// KeyStream returns n bytes of keystream starting at offset.
+func (c *CTR) KeyStream(offset int64, n int) []byte {
+	ks := make([]byte, n)
+	c.XORKeyStreamAt(ks, ks, offset)
+	return ks
+}
+
+// Seek sets the position for the next XORKeyStream call.
+func (c *CTR) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += c.offset
+	default:
+		return 0, fmt.Errorf("unsupported whence %d", whence)
+	}
+	if offset < 0 {
+		return 0, errors.New("negative keystream position")
+	}
+	c.offset = offset
+	return offset, nil
+}
+
+// keystreamBlock returns the encryption of the counter block for index.
+func (c *CTR) keystreamBlock(index int64) []byte {
+	if index != c.cachedIndex {
+		c.block.Encrypt(c.cached, c.counterBlock(index))
+		c.cachedIndex = index
+	}
+	return c.cached
+}
+
+func (c *CTR) counterBlock(index int64) []byte {
+	ctr := make([]byte, aes.BlockSize)
+	copy(ctr, c.nonce)
+
+	if c.layout == LittleEndian64 {
+		binary.LittleEndian.PutUint64(ctr[8:], uint64(index))
+		return ctr
+	}
+
+	// Add index to the 128-bit big-endian nonce.
+	lo := binary.BigEndian.Uint64(ctr[8:])
+	hi := binary.BigEndian.Uint64(ctr[:8])
+	sum := lo + uint64(index)
+	if sum < lo {
+		hi++
+	}
+	binary.BigEndian.PutUint64(ctr[:8], hi)
+	binary.BigEndian.PutUint64(ctr[8:], sum)
+	return ctr
+}
+
+// CTRCrypt encrypts or decrypts data with AES-CTR starting at counter 0.
+func CTRCrypt(data, key, nonce []byte, layout CounterLayout) ([]byte, error) {
+	stream, err := NewCTR(key, nonce, layout)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, len(data))
+	stream.XORKeyStream(out, data)
+	return out, nil
+}
+
+// NewCTRReader returns a reader that decrypts (or encrypts) everything read
+// from r.
+func NewCTRReader(r io.Reader, key, nonce []byte, layout CounterLayout) (io.Reader, error) {
+	stream, err := NewCTR(key, nonce, layout)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.StreamReader{S: stream, R: r}, nil
+}
+
+// NewCTRWriter returns a writer that encrypts (or decrypts) everything written
+// to it before passing it on to w. Closing it closes w if w is an io.Closer.
+func NewCTRWriter(w io.Writer, key, nonce []byte, layout CounterLayout) (io.WriteCloser, error) {
+	stream, err := NewCTR(key, nonce, layout)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.StreamWriter{S: stream, W: w}, nil
+}
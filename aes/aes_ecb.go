@@ -0,0 +1,39 @@
+package aescbc
+
+import (
+	"crypto/aes"
+	"fmt"
+)
+
+// ECBEncrypt pads pt and encrypts each block independently under key.
+func ECBEncrypt(pt, key []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	padded := pkcs5(append([]byte{}, pt...), aes.BlockSize)
+	ct := make([]byte, len(padded))
+	for i := 0; i < len(padded); i += aes.BlockSize {
+		block.Encrypt(ct[i:i+aes.BlockSize], padded[i:i+aes.BlockSize])
+	}
+	return ct, nil
+}
+
+// ECBDecrypt decrypts each block of ct independently and removes the padding.
+func ECBDecrypt(ct, key []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ct)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("ciphertext of length %d is not multiple of block size", len(ct))
+	}
+
+	pt := make([]byte, len(ct))
+	for i := 0; i < len(ct); i += aes.BlockSize {
+		block.Decrypt(pt[i:i+aes.BlockSize], ct[i:i+aes.BlockSize])
+	}
+	return removePadding(pt, aes.BlockSize)
+}
@@ -0,0 +1,15 @@
+package aescbc
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+)
+
+// NewGCM returns AES-GCM with the standard 12-byte nonce and 16-byte tag.
+func NewGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
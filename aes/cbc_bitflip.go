@@ -0,0 +1,105 @@
+package aescbc
+
+import (
+	"bytes"
+	"crypto/aes"
+	"errors"
+	"io"
+
+	"github.com/jessesomerville/cryptopals_set5/randutil"
+)
+
+// Fixed strings the cookie oracle puts around the user data.
+const (
+	CookiePrefix = "comment1=cooking%20MCs;userdata="
+	CookieSuffix = ";comment2=%20like%20a%20pound%20of%20bacon"
+)
+
+// CookieOracle builds cookie strings around user data and encrypts them with
+// CBC under a fixed random key.
+type CookieOracle struct {
+	key    []byte
+	random io.Reader
+
+	// Authenticated switches the oracle to encrypt-then-MAC, so tampered
+	// cookies are rejected before they are decrypted.
+	Authenticated bool
+}
+
+// NewCookieOracle creates an oracle with a random AES-128 key. The key and
+// IVs are read from random, or from crypto/rand if random is nil.
+func NewCookieOracle(random io.Reader) (*CookieOracle, error) {
+	key := make([]byte, 16)
+	if err := randutil.Read(random, key); err != nil {
+		return nil, err
+	}
+	return &CookieOracle{key: key, random: random}, nil
+}
+
+// Encrypt quotes out ';' and '=' in userdata, places it between CookiePrefix
+// and CookieSuffix, and encrypts the result.
+func (o *CookieOracle) Encrypt(userdata []byte) ([]byte, error) {
+	pt := cookie(userdata)
+	if o.Authenticated {
+		return EncryptAuthenticated(o.random, pt, o.key)
+	}
+	return Encrypt(o.random, pt, o.key)
+}
+
+// IsAdmin decrypts the cookie and reports whether it contains ";admin=true;".
+func (o *CookieOracle) IsAdmin(ct []byte) (bool, error) {
+	var pt []byte
+	var err error
+	if o.Authenticated {
+		pt, err = DecryptAuthenticated(ct, o.key)
+	} else {
+		pt, err = Decrypt(ct, o.key)
+	}
+	if err != nil {
+		return false, err
+	}
+	return bytes.Contains(pt, []byte(";admin=true;")), nil
+}
+
+// cookie quotes out ';' and '=' in userdata and places it between
+// CookiePrefix and CookieSuffix.
+func cookie(userdata []byte) []byte {
+	quoted := bytes.ReplaceAll(userdata, []byte(";"), []byte("%3B"))
+	quoted = bytes.ReplaceAll(quoted, []byte("="), []byte("%3D"))
+
+	pt := append([]byte(CookiePrefix), quoted...)
+	return append(pt, CookieSuffix...)
+}
+
+// CBCBitflipAttack forges an admin cookie from the oracle's encrypt function.
+// The user data pads CookiePrefix out to a block boundary, adds a sacrificial
+// block, and then a block holding ":admin<true:". Flipping the low bit of the
+// matching bytes in the sacrificial ciphertext block turns ':' into ';' and
+// '<' into '=' in the decrypted block after it.
+func CBCBitflipAttack(encrypt func(userdata []byte) ([]byte, error)) ([]byte, error) {
+	align := (aes.BlockSize - len(CookiePrefix)%aes.BlockSize) % aes.BlockSize
+	payload := []byte(":admin<true:")
+
+	userdata := bytes.Repeat([]byte{'A'}, align+aes.BlockSize)
+	userdata = append(userdata, payload...)
+
+	ct, err := encrypt(userdata)
+	if err != nil {
+		return nil, err
+	}
+
+	// The ciphertext starts with the IV, so the sacrificial plaintext block
+	// is encrypted one block further in.
+	sacrificial := aes.BlockSize + len(CookiePrefix) + align
+	if len(ct) < sacrificial+2*aes.BlockSize {
+		return nil, errors.New("ciphertext is too short")
+	}
+
+	forged := append([]byte{}, ct...)
+	for i, b := range payload {
+		if b == ':' || b == '<' {
+			forged[sacrificial+i] ^= 1
+		}
+	}
+	return forged, nil
+}
@@ -0,0 +1,121 @@
+package aescbc
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/jessesomerville/cryptopals_set5/randutil"
+)
+
+// EncryptKeyIV pads pt and encrypts it with AES-CBC using the key as the IV.
+// The IV is not sent, since the receiver already knows the key. Never do this.
+func EncryptKeyIV(pt, key []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	padded := pkcs5(append([]byte{}, pt...), aes.BlockSize)
+	cipher.NewCBCEncrypter(block, key).CryptBlocks(padded, padded)
+	return padded, nil
+}
+
+// DecryptKeyIV decrypts ct with AES-CBC using the key as the IV and removes
+// the padding.
+func DecryptKeyIV(ct, key []byte) ([]byte, error) {
+	pt, err := decryptKeyIVRaw(ct, key)
+	if err != nil {
+		return nil, err
+	}
+	return removePadding(pt, aes.BlockSize)
+}
+
+func decryptKeyIVRaw(ct, key []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ct) == 0 || len(ct)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("ciphertext of length %d is not multiple of block size", len(ct))
+	}
+
+	pt := make([]byte, len(ct))
+	cipher.NewCBCDecrypter(block, key).CryptBlocks(pt, ct)
+	return pt, nil
+}
+
+// HighASCIIError is returned by KeyIVReceiver when a decrypted message
+// contains bytes above 0x7f. Like the vendor product it is modelled on, it
+// includes the offending plaintext.
+type HighASCIIError struct {
+	Plaintext []byte
+}
+
+func (e *HighASCIIError) Error() string {
+	return fmt.Sprintf("message contains high-ASCII bytes: %q", e.Plaintext)
+}
+
+// KeyIVReceiver decrypts messages encrypted with EncryptKeyIV and rejects any
+// that are not plain ASCII.
+type KeyIVReceiver struct {
+	key []byte
+}
+
+// NewKeyIVReceiver creates a receiver with a random AES-128 key read from
+// random, or from crypto/rand if random is nil.
+func NewKeyIVReceiver(random io.Reader) (*KeyIVReceiver, error) {
+	key := make([]byte, 16)
+	if err := randutil.Read(random, key); err != nil {
+		return nil, err
+	}
+	return &KeyIVReceiver{key: key}, nil
+}
+
+// Encrypt encrypts a message for the receiver, as the sender would.
+func (r *KeyIVReceiver) Encrypt(pt []byte) ([]byte, error) {
+	return EncryptKeyIV(pt, r.key)
+}
+
+// Receive decrypts ct and checks it for high-ASCII bytes before checking the
+// padding.
+func (r *KeyIVReceiver) Receive(ct []byte) error {
+	pt, err := decryptKeyIVRaw(ct, r.key)
+	if err != nil {
+		return err
+	}
+	for _, b := range pt {
+		if b > 0x7f {
+			return &HighASCIIError{Plaintext: pt}
+		}
+	}
+	_, err = removePadding(pt, aes.BlockSize)
+	return err
+}
+
+// RecoverKeyIV recovers the key from a ciphertext of at least three blocks.
+// It sends C1 || 0 || C1 followed by the rest of the ciphertext. The first
+// plaintext block comes out as D(C1) ^ key and the third as D(C1) ^ 0, so
+// XORing the two blocks from the receiver's error message gives the key.
+func RecoverKeyIV(ct []byte, receive func([]byte) error) ([]byte, error) {
+	if len(ct) < 3*aes.BlockSize {
+		return nil, fmt.Errorf("ciphertext of length %d is shorter than three blocks", len(ct))
+	}
+
+	c1 := ct[:aes.BlockSize]
+	forged := make([]byte, 0, len(ct))
+	forged = append(forged, c1...)
+	forged = append(forged, make([]byte, aes.BlockSize)...)
+	forged = append(forged, c1...)
+	forged = append(forged, ct[3*aes.BlockSize:]...)
+
+	var highASCII *HighASCIIError
+	if err := receive(forged); !errors.As(err, &highASCII) {
+		return nil, fmt.Errorf("receiver did not leak the plaintext: %v", err)
+	}
+
+	pt := highASCII.Plaintext
+	return xorBytes(pt[:aes.BlockSize], pt[2*aes.BlockSize:3*aes.BlockSize]), nil
+}
@@ -0,0 +1,45 @@
+package aescbc
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"fmt"
+)
+
+// CBCMAC pads msg and returns the last ciphertext block of its AES-CBC
+// encryption under key and iv. It is only secure for fixed-length messages
+// under a fixed IV.
+func CBCMAC(msg, key, iv []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != aes.BlockSize {
+		return nil, fmt.Errorf("IV of length %d is not one block", len(iv))
+	}
+
+	padded := pkcs5(append([]byte{}, msg...), aes.BlockSize)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(padded, padded)
+	return padded[len(padded)-aes.BlockSize:], nil
+}
+
+// CBCMACCollision builds a message that starts with chosen and has the same
+// CBC-MAC as target. The chosen content is padded as CBCMAC would pad it, and
+// a glue block XORs its MAC and the IV into the first block of target, so the
+// CBC state after the glue block matches the state after target's first block
+// and the rest of target finishes the chain.
+func CBCMACCollision(target, chosen, key, iv []byte) ([]byte, error) {
+	if len(target) < aes.BlockSize {
+		return nil, fmt.Errorf("target of length %d is shorter than a block", len(target))
+	}
+
+	state, err := CBCMAC(chosen, key, iv)
+	if err != nil {
+		return nil, err
+	}
+
+	forged := pkcs5(append([]byte{}, chosen...), aes.BlockSize)
+	glue := xorBytes(xorBytes(target[:aes.BlockSize], iv), state)
+	forged = append(forged, glue...)
+	return append(forged, target[aes.BlockSize:]...), nil
+}
@@ -0,0 +1,44 @@
+package aescbc
+
+import (
+	"bytes"
+	"crypto/aes"
+	"testing"
+)
+
+func TestCBCMACCollision(t *testing.T) {
+	key := []byte("YELLOW SUBMARINE")
+	target := []byte("alert('MZA who was that?');\n")
+	chosen := []byte("alert('Ayo, the Wu is back!');//")
+
+	tests := []struct {
+		name string
+		iv   []byte
+	}{
+		{"zero IV", make([]byte, aes.BlockSize)},
+		{"non-zero IV", []byte("0123456789abcdef")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forged, err := CBCMACCollision(target, chosen, key, tt.iv)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.HasPrefix(forged, chosen) {
+				t.Errorf("forged message %q does not start with the chosen content", forged)
+			}
+
+			want, err := CBCMAC(target, key, tt.iv)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := CBCMAC(forged, key, tt.iv)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("CBCMAC(forged) = %x, want %x", got, want)
+			}
+		})
+	}
+}
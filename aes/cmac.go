@@ -0,0 +1,64 @@
+package aescbc
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+)
+
+// CMAC returns the AES-CMAC (RFC 4493) of msg under key. Unlike CBCMAC it is
+// secure for messages of any length: the last block is masked with a subkey
+// derived from the key, so it cannot be extended.
+func CMAC(msg, key []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cmac(block, msg), nil
+}
+
+func cmac(block cipher.Block, msg []byte) []byte {
+	l := make([]byte, aes.BlockSize)
+	block.Encrypt(l, l)
+	k1 := dbl(l)
+	k2 := dbl(k1)
+
+	// Every block but the last is plain CBC-MAC with a zero IV.
+	n := (len(msg) + aes.BlockSize - 1) / aes.BlockSize
+	if n == 0 {
+		n = 1
+	}
+	state := make([]byte, aes.BlockSize)
+	for i := 0; i < n-1; i++ {
+		state = xorBytes(state, msg[i*aes.BlockSize:(i+1)*aes.BlockSize])
+		block.Encrypt(state, state)
+	}
+
+	// A complete last block is masked with K1. A partial one is padded with
+	// a single 1 bit and zeros and masked with K2.
+	last := make([]byte, aes.BlockSize)
+	rest := copy(last, msg[(n-1)*aes.BlockSize:])
+	if rest == aes.BlockSize {
+		last = xorBytes(last, k1)
+	} else {
+		last[rest] = 0x80
+		last = xorBytes(last, k2)
+	}
+
+	state = xorBytes(state, last)
+	block.Encrypt(state, state)
+	return state
+}
+
+// dbl multiplies a block by x in GF(2^128), as CMAC and S2V do to derive
+// distinct masks from one value.
+func dbl(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i := 0; i < len(b)-1; i++ {
+		out[i] = b[i]<<1 | b[i+1]>>7
+	}
+	out[len(b)-1] = b[len(b)-1] << 1
+	if b[0]&0x80 != 0 {
+		out[len(b)-1] ^= 0x87
+	}
+	return out
+}
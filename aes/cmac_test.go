@@ -0,0 +1,58 @@
+package aescbc
+
+import (
+	"bytes"
+	"crypto/aes"
+	"testing"
+)
+
+// RFC 4493 section 4.
+func TestCMAC(t *testing.T) {
+	key := decodeSpaced(t, "2b7e1516 28aed2a6 abf71588 09cf4f3c")
+	msg := decodeSpaced(t, "6bc1bee2 2e409f96 e93d7e11 7393172a ae2d8a57 1e03ac9c 9eb76fac 45af8e51"+
+		"30c81c46 a35ce411 e5fbc119 1a0a52ef f69f2445 df4f9b17 ad2b417b e66c3710")
+
+	tests := []struct {
+		name string
+		len  int
+		tag  string
+	}{
+		{"Example 1", 0, "bb1d6929 e9593728 7fa37d12 9b756746"},
+		{"Example 2", 16, "070a16b4 6b4d4144 f79bdd9d d04a287c"},
+		{"Example 3", 40, "dfa66747 de9ae630 30ca3261 1497c827"},
+		{"Example 4", 64, "51f0bebf 7e3b9d92 fc497417 79363cfe"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := decodeSpaced(t, tt.tag)
+			got, err := CMAC(msg[:tt.len], key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("CMAC() = %x, want %x", got, want)
+			}
+		})
+	}
+}
+
+// RFC 4493 section 4, subkey generation.
+func TestCMACSubkeys(t *testing.T) {
+	block, err := aes.NewCipher(decodeSpaced(t, "2b7e1516 28aed2a6 abf71588 09cf4f3c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := make([]byte, aes.BlockSize)
+	block.Encrypt(l, l)
+
+	if want := decodeSpaced(t, "7df76b0c 1ab899b3 3e42f047 b91b546f"); !bytes.Equal(l, want) {
+		t.Fatalf("L = %x, want %x", l, want)
+	}
+	k1 := dbl(l)
+	if want := decodeSpaced(t, "fbeed618 35713366 7c85e08f 7236a8de"); !bytes.Equal(k1, want) {
+		t.Errorf("K1 = %x, want %x", k1, want)
+	}
+	if k2, want := dbl(k1), decodeSpaced(t, "f7ddac30 6ae266cc f90bc11e e46d513b"); !bytes.Equal(k2, want) {
+		t.Errorf("K2 = %x, want %x", k2, want)
+	}
+}
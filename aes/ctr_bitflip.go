@@ -0,0 +1,83 @@
+package aescbc
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/jessesomerville/cryptopals_set5/randutil"
+)
+
+// CTRCookieOracle builds the same cookies as CookieOracle but encrypts them
+// with CTR under a fixed random key. Each cookie gets a fresh random nonce,
+// sent in front of the ciphertext.
+type CTRCookieOracle struct {
+	key    []byte
+	random io.Reader
+}
+
+// NewCTRCookieOracle creates an oracle with a random AES-128 key. The key and
+// nonces are read from random, or from crypto/rand if random is nil.
+func NewCTRCookieOracle(random io.Reader) (*CTRCookieOracle, error) {
+	key := make([]byte, 16)
+	if err := randutil.Read(random, key); err != nil {
+		return nil, err
+	}
+	return &CTRCookieOracle{key: key, random: random}, nil
+}
+
+// Encrypt quotes out ';' and '=' in userdata, places it between CookiePrefix
+// and CookieSuffix, and encrypts the result.
+func (o *CTRCookieOracle) Encrypt(userdata []byte) ([]byte, error) {
+	nonce := make([]byte, LittleEndian64.NonceSize())
+	if err := randutil.Read(o.random, nonce); err != nil {
+		return nil, err
+	}
+
+	ct, err := CTRCrypt(cookie(userdata), o.key, nonce, LittleEndian64)
+	if err != nil {
+		return nil, err
+	}
+	return append(nonce, ct...), nil
+}
+
+// IsAdmin decrypts the cookie and reports whether it contains ";admin=true;".
+func (o *CTRCookieOracle) IsAdmin(ct []byte) (bool, error) {
+	nonceSize := LittleEndian64.NonceSize()
+	if len(ct) < nonceSize {
+		return false, errors.New("ciphertext is too short")
+	}
+
+	pt, err := CTRCrypt(ct[nonceSize:], o.key, ct[:nonceSize], LittleEndian64)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Contains(pt, []byte(";admin=true;")), nil
+}
+
+// CTRBitflipAttack forges an admin cookie from the oracle's encrypt function.
+// Flipping a ciphertext bit in CTR flips the same plaintext bit and nothing
+// else, so no sacrificial block is needed: the low bits of ':' and '<' in
+// ":admin<true:" are flipped directly.
+func CTRBitflipAttack(encrypt func(userdata []byte) ([]byte, error)) ([]byte, error) {
+	payload := []byte(":admin<true:")
+
+	ct, err := encrypt(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// The ciphertext starts with the nonce.
+	start := LittleEndian64.NonceSize() + len(CookiePrefix)
+	if len(ct) < start+len(payload) {
+		return nil, errors.New("ciphertext is too short")
+	}
+
+	forged := append([]byte{}, ct...)
+	for i, b := range payload {
+		if b == ':' || b == '<' {
+			forged[start+i] ^= 1
+		}
+	}
+	return forged, nil
+}
@@ -0,0 +1,85 @@
+package aescbc
+
+// englishFreq holds the relative frequency of lowercase letters and space in
+// English text.
+var englishFreq = map[byte]float64{
+	'a': 0.0651738, 'b': 0.0124248, 'c': 0.0217339, 'd': 0.0349835, 'e': 0.1041442,
+	'f': 0.0197881, 'g': 0.0158610, 'h': 0.0492888, 'i': 0.0558094, 'j': 0.0009033,
+	'k': 0.0050529, 'l': 0.0331490, 'm': 0.0202124, 'n': 0.0564513, 'o': 0.0596302,
+	'p': 0.0137645, 'q': 0.0008606, 'r': 0.0497563, 's': 0.0515760, 't': 0.0729357,
+	'u': 0.0225134, 'v': 0.0082903, 'w': 0.0171272, 'x': 0.0013692, 'y': 0.0145984,
+	'z': 0.0007836, ' ': 0.1918182,
+}
+
+// ScoreEnglish rates how much text looks like English, higher being more
+// likely. Letters and spaces score by their frequency, other printable
+// characters score nothing, and control or high bytes are penalised.
+func ScoreEnglish(text []byte) float64 {
+	score := 0.0
+	for _, c := range text {
+		switch {
+		case c >= 'A' && c <= 'Z':
+			score += englishFreq[c+'a'-'A']
+		case englishFreq[c] > 0:
+			score += englishFreq[c]
+		case c == '\n' || (c >= 0x20 && c < 0x7f):
+		default:
+			score -= 1
+		}
+	}
+	return score
+}
+
+// BreakFixedNonceCTR recovers the keystream shared by ciphertexts encrypted
+// with CTR under the same key and nonce. Byte i of every ciphertext was XOR'd
+// with the same keystream byte, so each column is a single-byte XOR that can
+// be broken by picking the key byte that makes the column look most like
+// English. Columns covered by only a few ciphertexts are less reliable and
+// may need correcting with CorrectKeystream.
+func BreakFixedNonceCTR(cts [][]byte) []byte {
+	maxLen := 0
+	for _, ct := range cts {
+		if len(ct) > maxLen {
+			maxLen = len(ct)
+		}
+	}
+
+	keystream := make([]byte, maxLen)
+	column := make([]byte, 0, len(cts))
+	decrypted := make([]byte, len(cts))
+	for i := range keystream {
+		column = column[:0]
+		for _, ct := range cts {
+			if i < len(ct) {
+				column = append(column, ct[i])
+			}
+		}
+
+		best := -1e9
+		for k := 0; k < 256; k++ {
+			for j, c := range column {
+				decrypted[j] = c ^ byte(k)
+			}
+			if score := ScoreEnglish(decrypted[:len(column)]); score > best {
+				best = score
+				keystream[i] = byte(k)
+			}
+		}
+	}
+	return keystream
+}
+
+// CorrectKeystream fixes the keystream byte at pos so that ct decrypts to want
+// at that position.
+func CorrectKeystream(keystream, ct []byte, pos int, want byte) {
+	keystream[pos] = ct[pos] ^ want
+}
+
+// XORKeystream decrypts ct with as much of the keystream as it covers.
+func XORKeystream(ct, keystream []byte) []byte {
+	n := len(ct)
+	if len(keystream) < n {
+		n = len(keystream)
+	}
+	return xorBytes(ct[:n], keystream[:n])
+}
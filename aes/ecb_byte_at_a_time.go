@@ -0,0 +1,234 @@
+package aescbc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/jessesomerville/cryptopals_set5/randutil"
+)
+
+// maxBlockSize bounds the block size search in ByteAtATimeECB.
+const maxBlockSize = 64
+
+// ECBSuffixOracle encrypts attacker input followed by a secret with ECB under
+// a fixed key. It can also put a fixed random-length random prefix in front of
+// the input.
+type ECBSuffixOracle struct {
+	key    []byte
+	prefix []byte
+	secret []byte
+	// Number of times Encrypt has been called
+	Calls int
+}
+
+// NewECBSuffixOracle creates an oracle with a random key that hides secret.
+// If randomPrefix is set, between 0 and 63 random bytes are put before the
+// input on every call. Randomness is read from random, or from crypto/rand if
+// random is nil.
+func NewECBSuffixOracle(random io.Reader, secret []byte, randomPrefix bool) (*ECBSuffixOracle, error) {
+	oracle := &ECBSuffixOracle{
+		key:    make([]byte, 16),
+		secret: append([]byte{}, secret...),
+	}
+	if err := randutil.Read(random, oracle.key); err != nil {
+		return nil, err
+	}
+
+	if randomPrefix {
+		n, err := randutil.Int(random, big.NewInt(64))
+		if err != nil {
+			return nil, err
+		}
+		oracle.prefix = make([]byte, n.Int64())
+		if err := randutil.Read(random, oracle.prefix); err != nil {
+			return nil, err
+		}
+	}
+	return oracle, nil
+}
+
+// Encrypt returns ECB(prefix || input || secret).
+func (o *ECBSuffixOracle) Encrypt(input []byte) ([]byte, error) {
+	o.Calls++
+	pt := make([]byte, 0, len(o.prefix)+len(input)+len(o.secret))
+	pt = append(pt, o.prefix...)
+	pt = append(pt, input...)
+	pt = append(pt, o.secret...)
+	return ECBEncrypt(pt, o.key)
+}
+
+// ByteAtATimeOptions selects between attack strategies.
+type ByteAtATimeOptions struct {
+	// Batch submits all 256 candidate blocks for a byte in a single oracle
+	// call instead of one call per candidate.
+	Batch bool
+}
+
+// ByteAtATimeResult holds the recovered secret and what was learned about
+// the oracle along the way.
+type ByteAtATimeResult struct {
+	Secret      []byte
+	BlockSize   int
+	PrefixLen   int
+	OracleCalls int
+}
+
+// ByteAtATimeECB recovers the secret an ECB oracle appends to its input. It
+// finds the block size from the jump in ciphertext length, confirms the
+// oracle uses ECB, measures any constant prefix, and then decrypts the secret
+// one byte at a time: a short input pushes the next unknown byte to the end of
+// a block, and that block is matched against a dictionary of every possible
+// last byte.
+func ByteAtATimeECB(oracle func(input []byte) ([]byte, error), opts ByteAtATimeOptions) (*ByteAtATimeResult, error) {
+	result := &ByteAtATimeResult{}
+	query := func(input []byte) ([]byte, error) {
+		result.OracleCalls++
+		return oracle(input)
+	}
+
+	// Block size: the ciphertext grows by a whole block once the input
+	// pushes the padding over a block boundary.
+	empty, err := query(nil)
+	if err != nil {
+		return nil, err
+	}
+	totalLen := -1
+	for i := 1; i <= maxBlockSize; i++ {
+		ct, err := query(bytes.Repeat([]byte{'A'}, i))
+		if err != nil {
+			return nil, err
+		}
+		if len(ct) > len(empty) {
+			result.BlockSize = len(ct) - len(empty)
+			totalLen = len(empty) - i
+			break
+		}
+	}
+	if totalLen < 0 {
+		return nil, errors.New("could not determine the block size")
+	}
+	bs := result.BlockSize
+
+	mode, err := DetectMode(query)
+	if err != nil {
+		return nil, err
+	}
+	if mode != ModeECB {
+		return nil, errors.New("oracle does not use ECB")
+	}
+
+	result.PrefixLen, err = findPrefixLen(query, bs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pad the prefix out to a block boundary so the attacker controls whole
+	// blocks from start onwards.
+	align := make([]byte, (bs-result.PrefixLen%bs)%bs)
+	start := result.PrefixLen + len(align)
+	secretLen := totalLen - result.PrefixLen
+
+	known := make([]byte, 0, secretLen)
+	for i := 0; i < secretLen; i++ {
+		fill := bytes.Repeat([]byte{'A'}, bs-1-i%bs)
+		ct, err := query(append(append([]byte{}, align...), fill...))
+		if err != nil {
+			return nil, err
+		}
+		offset := start + (i/bs)*bs
+		target := ct[offset : offset+bs]
+
+		// The dictionary block is the bs-1 bytes before the unknown byte
+		// followed by each candidate.
+		window := append(append([]byte{}, fill...), known...)
+		window = window[len(window)-(bs-1):]
+
+		b, err := matchByte(query, align, window, target, start, opts.Batch)
+		if err != nil {
+			return nil, fmt.Errorf("byte %d: %v", i, err)
+		}
+		known = append(known, b)
+	}
+
+	result.Secret = known
+	return result, nil
+}
+
+// findPrefixLen measures the prefix by growing the input until it contains two
+// identical aligned blocks. The check is repeated with two filler bytes so a
+// prefix ending in the filler cannot throw off the count.
+func findPrefixLen(query func([]byte) ([]byte, error), bs int) (int, error) {
+	for pad := 0; pad < bs; pad++ {
+		index := -1
+		for _, filler := range []byte{0x00, 0xff} {
+			input := append(bytes.Repeat([]byte{'B'}, pad), bytes.Repeat([]byte{filler}, 2*bs)...)
+			ct, err := query(input)
+			if err != nil {
+				return 0, err
+			}
+
+			i := firstRepeatedPair(ct, bs)
+			if i < 0 || (index >= 0 && i != index) {
+				index = -1
+				break
+			}
+			index = i
+		}
+		if index >= 0 {
+			return index*bs - pad, nil
+		}
+	}
+	return 0, errors.New("could not determine the prefix length")
+}
+
+// firstRepeatedPair returns the index of the first block that is equal to the
+// block after it, or -1.
+func firstRepeatedPair(ct []byte, bs int) int {
+	for i := 0; i+2*bs <= len(ct); i += bs {
+		if bytes.Equal(ct[i:i+bs], ct[i+bs:i+2*bs]) {
+			return i / bs
+		}
+	}
+	return -1
+}
+
+// matchByte finds the byte b such that the block window || b encrypts to
+// target. Dictionary blocks start at offset start in the ciphertext, right
+// after the aligned prefix.
+func matchByte(query func([]byte) ([]byte, error), align, window, target []byte, start int, batch bool) (byte, error) {
+	bs := len(target)
+
+	if batch {
+		input := append([]byte{}, align...)
+		for c := 0; c < 256; c++ {
+			input = append(input, window...)
+			input = append(input, byte(c))
+		}
+		ct, err := query(input)
+		if err != nil {
+			return 0, err
+		}
+		for c := 0; c < 256; c++ {
+			offset := start + c*bs
+			if bytes.Equal(ct[offset:offset+bs], target) {
+				return byte(c), nil
+			}
+		}
+		return 0, errors.New("no dictionary entry matches")
+	}
+
+	for c := 0; c < 256; c++ {
+		input := append(append(append([]byte{}, align...), window...), byte(c))
+		ct, err := query(input)
+		if err != nil {
+			return 0, err
+		}
+		if bytes.Equal(ct[start:start+bs], target) {
+			return byte(c), nil
+		}
+	}
+	return 0, errors.New("no dictionary entry matches")
+}
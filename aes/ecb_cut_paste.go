@@ -0,0 +1,117 @@
+package aescbc
+
+import (
+	"crypto/aes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/jessesomerville/cryptopals_set5/randutil"
+)
+
+// Profile is a user profile as encoded by ProfileFor.
+type Profile struct {
+	Email string
+	UID   int
+	Role  string
+}
+
+// ProfileFor encodes a user profile for email as "email=...&uid=10&role=user".
+// Metacharacters are stripped from the email so it cannot add fields.
+func ProfileFor(email string) string {
+	email = strings.NewReplacer("&", "", "=", "").Replace(email)
+	return "email=" + email + "&uid=10&role=user"
+}
+
+// ParseProfile decodes a string produced by ProfileFor.
+func ParseProfile(s string) (*Profile, error) {
+	profile := &Profile{}
+	for _, pair := range strings.Split(s, "&") {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("malformed profile field %q", pair)
+		}
+
+		switch key {
+		case "email":
+			profile.Email = value
+		case "uid":
+			uid, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("malformed uid %q", value)
+			}
+			profile.UID = uid
+		case "role":
+			profile.Role = value
+		default:
+			return nil, fmt.Errorf("unknown profile field %q", key)
+		}
+	}
+	return profile, nil
+}
+
+// ProfileOracle hands out ECB encrypted profiles under a fixed random key.
+type ProfileOracle struct {
+	key []byte
+}
+
+// NewProfileOracle creates an oracle with a random AES-128 key read from
+// random, or from crypto/rand if random is nil.
+func NewProfileOracle(random io.Reader) (*ProfileOracle, error) {
+	key := make([]byte, 16)
+	if err := randutil.Read(random, key); err != nil {
+		return nil, err
+	}
+	return &ProfileOracle{key: key}, nil
+}
+
+// Encrypt encrypts the profile for email.
+func (o *ProfileOracle) Encrypt(email string) ([]byte, error) {
+	return ECBEncrypt([]byte(ProfileFor(email)), o.key)
+}
+
+// Decrypt decrypts and parses an encrypted profile.
+func (o *ProfileOracle) Decrypt(ct []byte) (*Profile, error) {
+	pt, err := ECBDecrypt(ct, o.key)
+	if err != nil {
+		return nil, err
+	}
+	return ParseProfile(string(pt))
+}
+
+// ECBCutAndPaste forges an encrypted profile with role=admin. One email lines
+// up "admin" and its padding as a block of its own. Another pushes "role="
+// to the end of a block. Since ECB encrypts every block independently, the
+// admin block can be pasted in place of the final "user" block.
+func ECBCutAndPaste(encrypt func(email string) ([]byte, error)) ([]byte, error) {
+	const prefix, middle = "email=", "&uid=10&role="
+
+	// Fill the first block so the next one starts with "admin".
+	fill := aes.BlockSize - len(prefix)%aes.BlockSize
+	adminBlock := pkcs5([]byte("admin"), aes.BlockSize)
+	email := strings.Repeat("A", fill) + string(adminBlock)
+	ct, err := encrypt(email)
+	if err != nil {
+		return nil, err
+	}
+	start := len(prefix) + fill
+	if len(ct) < start+aes.BlockSize {
+		return nil, fmt.Errorf("ciphertext is too short")
+	}
+	admin := ct[start : start+aes.BlockSize]
+
+	// Choose an email length that ends "role=" on a block boundary.
+	n := (aes.BlockSize - (len(prefix)+len(middle))%aes.BlockSize) % aes.BlockSize
+	if n < len("@x.io") {
+		n += aes.BlockSize
+	}
+	email = strings.Repeat("a", n-len("@x.io")) + "@x.io"
+	ct, err = encrypt(email)
+	if err != nil {
+		return nil, err
+	}
+	end := len(prefix) + len(email) + len(middle)
+
+	return append(append([]byte{}, ct[:end]...), admin...), nil
+}
@@ -0,0 +1,102 @@
+package aescbc
+
+import (
+	"crypto/aes"
+	"io"
+	"math/big"
+
+	"github.com/jessesomerville/cryptopals_set5/randutil"
+)
+
+// Mode is a block cipher mode of operation.
+type Mode int
+
+const (
+	ModeECB Mode = iota
+	ModeCBC
+)
+
+func (m Mode) String() string {
+	if m == ModeECB {
+		return "ECB"
+	}
+	return "CBC"
+}
+
+// EncryptionOracle encrypts input under a fresh random key, surrounded by 5 to
+// 10 random bytes on each side, using ECB or CBC with equal probability. The
+// mode it picked is returned so a detector's guess can be checked. Randomness
+// is read from random, or from crypto/rand if random is nil.
+func EncryptionOracle(random io.Reader, input []byte) ([]byte, Mode, error) {
+	key := make([]byte, 16)
+	if err := randutil.Read(random, key); err != nil {
+		return nil, 0, err
+	}
+
+	prefix, err := randomPadding(random)
+	if err != nil {
+		return nil, 0, err
+	}
+	suffix, err := randomPadding(random)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	pt := make([]byte, 0, len(prefix)+len(input)+len(suffix))
+	pt = append(pt, prefix...)
+	pt = append(pt, input...)
+	pt = append(pt, suffix...)
+
+	coin, err := randutil.Int(random, big.NewInt(2))
+	if err != nil {
+		return nil, 0, err
+	}
+	if coin.Sign() == 0 {
+		ct, err := ECBEncrypt(pt, key)
+		return ct, ModeECB, err
+	}
+	ct, err := Encrypt(random, pt, key)
+	return ct, ModeCBC, err
+}
+
+// DetectMode guesses whether an oracle encrypts with ECB or CBC. It submits
+// enough identical bytes to fill at least two aligned blocks whatever prefix
+// the oracle adds, and looks for repeated ciphertext blocks.
+func DetectMode(oracle func(input []byte) ([]byte, error)) (Mode, error) {
+	input := make([]byte, 3*aes.BlockSize)
+	ct, err := oracle(input)
+	if err != nil {
+		return 0, err
+	}
+	if HasRepeatedBlock(ct, aes.BlockSize) {
+		return ModeECB, nil
+	}
+	return ModeCBC, nil
+}
+
+// HasRepeatedBlock reports whether any blocksize-aligned block appears more
+// than once in ct.
+func HasRepeatedBlock(ct []byte, blocksize int) bool {
+	seen := make(map[string]bool)
+	for i := 0; i+blocksize <= len(ct); i += blocksize {
+		block := string(ct[i : i+blocksize])
+		if seen[block] {
+			return true
+		}
+		seen[block] = true
+	}
+	return false
+}
+
+// randomPadding returns between 5 and 10 random bytes.
+func randomPadding(random io.Reader) ([]byte, error) {
+	n, err := randutil.Int(random, big.NewInt(6))
+	if err != nil {
+		return nil, err
+	}
+	padding := make([]byte, 5+n.Int64())
+	if err := randutil.Read(random, padding); err != nil {
+		return nil, err
+	}
+	return padding, nil
+}
@@ -0,0 +1,162 @@
+package aescbc
+
+import (
+	"bytes"
+	"crypto/aes"
+	"errors"
+	"testing"
+)
+
+var (
+	fuzzKey = []byte("YELLOW SUBMARINE")
+	fuzzIV  = []byte("0123456789abcdef")
+)
+
+// fuzzKeyFor picks an AES-128, AES-192 or AES-256 key.
+func fuzzKeyFor(size uint8) []byte {
+	n := 16 + 8*int(size%3)
+	return bytes.Repeat([]byte{size}, n)
+}
+
+func FuzzCBCRoundTrip(f *testing.F) {
+	f.Add([]byte(""), uint8(0))
+	f.Add([]byte("YELLOW SUBMARINE"), uint8(1))
+	f.Add(bytes.Repeat([]byte{0x10}, 47), uint8(2))
+
+	f.Fuzz(func(t *testing.T, pt []byte, size uint8) {
+		key := fuzzKeyFor(size)
+		ct, err := Encrypt(bytes.NewReader(fuzzIV), pt, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := aes.BlockSize + (len(pt)/aes.BlockSize+1)*aes.BlockSize; len(ct) != want {
+			t.Fatalf("Encrypt() returned %d bytes, want %d", len(ct), want)
+		}
+
+		got, err := Decrypt(ct, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, pt) {
+			t.Fatalf("Decrypt() = %x, want %x", got, pt)
+		}
+
+		// Every truncation that is not a whole number of blocks after the IV
+		// must be rejected rather than decrypted.
+		for n := 0; n < len(ct); n++ {
+			if n >= 2*aes.BlockSize && n%aes.BlockSize == 0 {
+				continue
+			}
+			if _, err := Decrypt(ct[:n], key); err == nil {
+				t.Fatalf("Decrypt() accepted ciphertext truncated to %d bytes", n)
+			}
+		}
+	})
+}
+
+func FuzzCBCDecrypt(f *testing.F) {
+	f.Add([]byte{})
+	f.Add(bytes.Repeat([]byte{0}, 2*aes.BlockSize))
+	f.Add(bytes.Repeat([]byte{0xff}, 3*aes.BlockSize-1))
+
+	f.Fuzz(func(t *testing.T, ct []byte) {
+		pt, err := Decrypt(ct, fuzzKey)
+		if err != nil {
+			return
+		}
+		if len(ct) < 2*aes.BlockSize || len(ct)%aes.BlockSize != 0 {
+			t.Fatalf("Decrypt() accepted malformed ciphertext of length %d", len(ct))
+		}
+
+		// A ciphertext with valid padding is exactly what Encrypt produces
+		// for the same IV.
+		again, err := Encrypt(bytes.NewReader(ct[:aes.BlockSize]), pt, fuzzKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(again, ct) {
+			t.Fatalf("Encrypt(Decrypt(%x)) = %x", ct, again)
+		}
+	})
+}
+
+func FuzzAuthenticatedTamper(f *testing.F) {
+	f.Add([]byte("amount=10"), uint16(0), uint8(0))
+	f.Add([]byte(""), uint16(40), uint8(7))
+
+	f.Fuzz(func(t *testing.T, pt []byte, pos uint16, bit uint8) {
+		ct, err := EncryptAuthenticated(bytes.NewReader(fuzzIV), pt, fuzzKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := DecryptAuthenticated(ct, fuzzKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, pt) {
+			t.Fatalf("DecryptAuthenticated() = %x, want %x", got, pt)
+		}
+
+		ct[int(pos)%len(ct)] ^= 1 << (bit % 8)
+		if _, err := DecryptAuthenticated(ct, fuzzKey); !errors.Is(err, ErrAuthentication) {
+			t.Fatalf("DecryptAuthenticated() of modified ciphertext returned %v, want %v", err, ErrAuthentication)
+		}
+	})
+}
+
+func FuzzCTRRoundTrip(f *testing.F) {
+	f.Add([]byte("YELLOW SUBMARINE"), uint16(3))
+	f.Add(bytes.Repeat([]byte{0}, 70), uint16(33))
+
+	f.Fuzz(func(t *testing.T, pt []byte, offset uint16) {
+		nonce := fuzzIV[:LittleEndian64.NonceSize()]
+		ct, err := CTRCrypt(pt, fuzzKey, nonce, LittleEndian64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := CTRCrypt(ct, fuzzKey, nonce, LittleEndian64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, pt) {
+			t.Fatalf("CTRCrypt(CTRCrypt(pt)) = %x, want %x", got, pt)
+		}
+		if len(pt) == 0 {
+			return
+		}
+
+		// Decrypting a suffix from its offset gives the same plaintext.
+		off := int(offset) % len(pt)
+		stream, err := NewCTR(fuzzKey, nonce, LittleEndian64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		suffix := make([]byte, len(ct)-off)
+		stream.XORKeyStreamAt(suffix, ct[off:], int64(off))
+		if !bytes.Equal(suffix, pt[off:]) {
+			t.Fatalf("XORKeyStreamAt(%d) = %x, want %x", off, suffix, pt[off:])
+		}
+	})
+}
+
+func FuzzECBDecrypt(f *testing.F) {
+	f.Add([]byte{})
+	f.Add(bytes.Repeat([]byte{0}, aes.BlockSize+1))
+
+	f.Fuzz(func(t *testing.T, ct []byte) {
+		pt, err := ECBDecrypt(ct, fuzzKey)
+		if err != nil {
+			return
+		}
+		if len(ct) == 0 || len(ct)%aes.BlockSize != 0 {
+			t.Fatalf("ECBDecrypt() accepted malformed ciphertext of length %d", len(ct))
+		}
+		again, err := ECBEncrypt(pt, fuzzKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(again, ct) {
+			t.Fatalf("ECBEncrypt(ECBDecrypt(%x)) = %x", ct, again)
+		}
+	})
+}
@@ -0,0 +1,181 @@
+package aescbc
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ErrKeyUnwrap matches any KeyUnwrapError with errors.Is.
+var ErrKeyUnwrap = errors.New("key unwrap integrity check failed")
+
+// KeyUnwrapError is returned when a wrapped key fails its integrity check,
+// either because it was modified or because the wrong KEK was used. Padded
+// reports whether it was unwrapped as an RFC 5649 padded key.
+type KeyUnwrapError struct {
+	Padded bool
+}
+
+func (e *KeyUnwrapError) Error() string {
+	if e.Padded {
+		return "padded " + ErrKeyUnwrap.Error()
+	}
+	return ErrKeyUnwrap.Error()
+}
+
+// Is reports whether target is ErrKeyUnwrap.
+func (e *KeyUnwrapError) Is(target error) bool {
+	return target == ErrKeyUnwrap
+}
+
+var (
+	// defaultIV is the initial value from RFC 3394 section 2.2.3.1.
+	defaultIV = []byte{0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6}
+	// paddedIVPrefix is the alternative initial value from RFC 5649 section 3.
+	paddedIVPrefix = []byte{0xa6, 0x59, 0x59, 0xa6}
+)
+
+// KeyWrap wraps key under kek as described in RFC 3394. The key must be a
+// multiple of 8 bytes and at least 16 bytes long.
+func KeyWrap(kek, key []byte) ([]byte, error) {
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) < 16 || len(key)%8 != 0 {
+		return nil, fmt.Errorf("key of length %d cannot be wrapped without padding", len(key))
+	}
+	return wrap(block, defaultIV, key), nil
+}
+
+// KeyUnwrap unwraps a key wrapped with KeyWrap, returning a KeyUnwrapError if
+// the integrity check fails.
+func KeyUnwrap(kek, wrapped []byte) ([]byte, error) {
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) < 24 || len(wrapped)%8 != 0 {
+		return nil, fmt.Errorf("wrapped key of length %d is invalid", len(wrapped))
+	}
+
+	iv, key := unwrap(block, wrapped)
+	if !bytes.Equal(iv, defaultIV) {
+		return nil, &KeyUnwrapError{}
+	}
+	return key, nil
+}
+
+// KeyWrapPad wraps a key of any non-zero length under kek as described in
+// RFC 5649.
+func KeyWrapPad(kek, key []byte) ([]byte, error) {
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) == 0 || uint64(len(key)) > 0xffffffff {
+		return nil, fmt.Errorf("key of length %d cannot be wrapped", len(key))
+	}
+
+	iv := make([]byte, 8)
+	copy(iv, paddedIVPrefix)
+	binary.BigEndian.PutUint32(iv[4:], uint32(len(key)))
+
+	padded := make([]byte, (len(key)+7)/8*8)
+	copy(padded, key)
+
+	// A single padded block is encrypted directly with AES.
+	if len(padded) == 8 {
+		out := append(iv, padded...)
+		block.Encrypt(out, out)
+		return out, nil
+	}
+	return wrap(block, iv, padded), nil
+}
+
+// KeyUnwrapPad unwraps a key wrapped with KeyWrapPad, returning a
+// KeyUnwrapError if the integrity check fails.
+func KeyUnwrapPad(kek, wrapped []byte) ([]byte, error) {
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) < 16 || len(wrapped)%8 != 0 {
+		return nil, fmt.Errorf("wrapped key of length %d is invalid", len(wrapped))
+	}
+
+	var iv, padded []byte
+	if len(wrapped) == 16 {
+		out := make([]byte, 16)
+		block.Decrypt(out, wrapped)
+		iv, padded = out[:8], out[8:]
+	} else {
+		iv, padded = unwrap(block, wrapped)
+	}
+
+	if !bytes.Equal(iv[:4], paddedIVPrefix) {
+		return nil, &KeyUnwrapError{Padded: true}
+	}
+	n := int(binary.BigEndian.Uint32(iv[4:]))
+	if n <= len(padded)-8 || n > len(padded) {
+		return nil, &KeyUnwrapError{Padded: true}
+	}
+	for _, b := range padded[n:] {
+		if b != 0 {
+			return nil, &KeyUnwrapError{Padded: true}
+		}
+	}
+	return padded[:n], nil
+}
+
+// wrap runs the RFC 3394 wrapping process W over the 64-bit blocks of pt,
+// using iv as the initial value.
+func wrap(block cipher.Block, iv, pt []byte) []byte {
+	n := len(pt) / 8
+	out := make([]byte, 8+len(pt))
+	copy(out, iv)
+	copy(out[8:], pt)
+
+	a := out[:8]
+	b := make([]byte, aes.BlockSize)
+	for j := 0; j < 6; j++ {
+		for i := 1; i <= n; i++ {
+			r := out[8*i : 8*i+8]
+			copy(b, a)
+			copy(b[8:], r)
+			block.Encrypt(b, b)
+
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(a, binary.BigEndian.Uint64(b[:8])^t)
+			copy(r, b[8:])
+		}
+	}
+	return out
+}
+
+// unwrap runs the RFC 3394 unwrapping process W^-1 and returns the recovered
+// initial value and key data for the caller to check.
+func unwrap(block cipher.Block, ct []byte) (iv, pt []byte) {
+	n := len(ct)/8 - 1
+	out := make([]byte, len(ct))
+	copy(out, ct)
+
+	a := out[:8]
+	b := make([]byte, aes.BlockSize)
+	for j := 5; j >= 0; j-- {
+		for i := n; i >= 1; i-- {
+			r := out[8*i : 8*i+8]
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(b, binary.BigEndian.Uint64(a)^t)
+			copy(b[8:], r)
+			block.Decrypt(b, b)
+
+			copy(a, b[:8])
+			copy(r, b[8:])
+		}
+	}
+	return out[:8], out[8:]
+}
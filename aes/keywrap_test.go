@@ -0,0 +1,95 @@
+package aescbc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// keyWrapVectors are the test vectors from RFC 3394 section 4 and RFC 5649
+// section 6.
+var keyWrapVectors = []struct {
+	name    string
+	kek     string
+	key     string
+	wrapped string
+	padded  bool
+}{
+	{"RFC 3394 4.1", "000102030405060708090a0b0c0d0e0f", "00112233445566778899aabbccddeeff", "1fa68b0a8112b447aef34bd8fb5a7b829d3e862371d2cfe5", false},
+	{"RFC 3394 4.2", "000102030405060708090a0b0c0d0e0f1011121314151617", "00112233445566778899aabbccddeeff", "96778b25ae6ca435f92b5b97c050aed2468ab8a17ad84e5d", false},
+	{"RFC 3394 4.3", "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f", "00112233445566778899aabbccddeeff", "64e8c3f9ce0f5ba263e9777905818a2a93c8191e7d6e8ae7", false},
+	{"RFC 3394 4.4", "000102030405060708090a0b0c0d0e0f1011121314151617", "00112233445566778899aabbccddeeff0001020304050607", "031d33264e15d33268f24ec260743edce1c6c7ddee725a936ba814915c6762d2", false},
+	{"RFC 3394 4.5", "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f", "00112233445566778899aabbccddeeff0001020304050607", "a8f9bc1612c68b3ff6e6f4fbe30e71e4769c8b80a32cb8958cd5d17d6b254da1", false},
+	{"RFC 3394 4.6", "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f", "00112233445566778899aabbccddeeff000102030405060708090a0b0c0d0e0f", "28c9f404c4b810f4cbccb35cfb87f8263f5786e2d80ed326cbc7f0e71a99f43bfb988b9b7a02dd21", false},
+	{"RFC 5649 6 (20 octets)", "5840df6e29b02af1ab493b705bf16ea1ae8338f4dcc176a8", "c37b7e6492584340bed12207808941155068f738", "138bdeaa9b8fa7fc61f97742e72248ee5ae6ae5360d1ae6a5f54f373fa543b6a", true},
+	{"RFC 5649 6 (7 octets)", "5840df6e29b02af1ab493b705bf16ea1ae8338f4dcc176a8", "466f7250617369", "afbeb0f07dfbf5419200f2ccb50bb24f", true},
+}
+
+func keyWrapFuncs(padded bool) (wrapFn func(kek, key []byte) ([]byte, error), unwrapFn func(kek, wrapped []byte) ([]byte, error)) {
+	if padded {
+		return KeyWrapPad, KeyUnwrapPad
+	}
+	return KeyWrap, KeyUnwrap
+}
+
+func TestKeyWrap(t *testing.T) {
+	for _, v := range keyWrapVectors {
+		t.Run(v.name, func(t *testing.T) {
+			kek, key, want := decodeHex(t, v.kek), decodeHex(t, v.key), decodeHex(t, v.wrapped)
+			wrapFn, unwrapFn := keyWrapFuncs(v.padded)
+
+			wrapped, err := wrapFn(kek, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(wrapped, want) {
+				t.Errorf("wrap = %x, want %x", wrapped, want)
+			}
+			unwrapped, err := unwrapFn(kek, want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(unwrapped, key) {
+				t.Errorf("unwrap = %x, want %x", unwrapped, key)
+			}
+		})
+	}
+}
+
+func TestKeyUnwrapIntegrity(t *testing.T) {
+	for _, v := range keyWrapVectors {
+		t.Run(v.name, func(t *testing.T) {
+			kek, wrapped := decodeHex(t, v.kek), decodeHex(t, v.wrapped)
+			_, unwrapFn := keyWrapFuncs(v.padded)
+
+			type unwrapCase struct {
+				name         string
+				kek, wrapped []byte
+			}
+			wrongKEK := append([]byte{}, kek...)
+			wrongKEK[0] ^= 1
+			cases := []unwrapCase{{"wrong KEK", wrongKEK, wrapped}}
+			for _, i := range []int{0, len(wrapped) / 2, len(wrapped) - 1} {
+				tampered := append([]byte{}, wrapped...)
+				tampered[i] ^= 0x80
+				cases = append(cases, unwrapCase{fmt.Sprintf("tampered byte %d", i), kek, tampered})
+			}
+
+			for _, c := range cases {
+				_, err := unwrapFn(c.kek, c.wrapped)
+				var unwrapErr *KeyUnwrapError
+				if !errors.As(err, &unwrapErr) {
+					t.Errorf("%s: unwrap error = %v, want *KeyUnwrapError", c.name, err)
+					continue
+				}
+				if unwrapErr.Padded != v.padded {
+					t.Errorf("%s: Padded = %t, want %t", c.name, unwrapErr.Padded, v.padded)
+				}
+				if !errors.Is(err, ErrKeyUnwrap) {
+					t.Errorf("%s: errors.Is(%v, ErrKeyUnwrap) = false", c.name, err)
+				}
+			}
+		})
+	}
+}
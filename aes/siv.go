@@ -0,0 +1,92 @@
+package aescbc
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"fmt"
+)
+
+// SIV is AES-SIV (RFC 5297) deterministic authenticated encryption. The
+// synthetic IV is a CMAC over the associated data and plaintext, and also
+// keys the CTR encryption, so encrypting the same input twice gives the same
+// ciphertext and reusing a nonce reveals nothing more than that.
+type SIV struct {
+	mac cipher.Block
+	ctr []byte
+}
+
+// NewSIV creates AES-SIV from a 32, 48 or 64 byte key. The first half keys
+// CMAC and the second half keys CTR.
+func NewSIV(key []byte) (*SIV, error) {
+	if len(key) != 32 && len(key) != 48 && len(key) != 64 {
+		return nil, fmt.Errorf("SIV key of length %d, need 32, 48 or 64", len(key))
+	}
+	mac, err := aes.NewCipher(key[:len(key)/2])
+	if err != nil {
+		return nil, err
+	}
+	return &SIV{mac: mac, ctr: append([]byte{}, key[len(key)/2:]...)}, nil
+}
+
+// Seal encrypts pt and returns the synthetic IV followed by the ciphertext.
+// Each element of ad is authenticated separately. For nonce-based use, pass
+// the nonce as the last element.
+func (s *SIV) Seal(pt []byte, ad ...[]byte) ([]byte, error) {
+	v := s.s2v(pt, ad)
+	ct, err := s.crypt(v, pt)
+	if err != nil {
+		return nil, err
+	}
+	return append(v, ct...), nil
+}
+
+// Open checks and decrypts a ciphertext from Seal under the same associated
+// data, returning ErrAuthentication if either was modified.
+func (s *SIV) Open(ct []byte, ad ...[]byte) ([]byte, error) {
+	if len(ct) < aes.BlockSize {
+		return nil, fmt.Errorf("ciphertext of length %d is too short", len(ct))
+	}
+	v := ct[:aes.BlockSize]
+
+	pt, err := s.crypt(v, ct[aes.BlockSize:])
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(v, s.s2v(pt, ad)) != 1 {
+		return nil, ErrAuthentication
+	}
+	return pt, nil
+}
+
+// s2v turns the associated data and plaintext into one CMAC, doubling the
+// running value between inputs so their order and boundaries matter.
+func (s *SIV) s2v(pt []byte, ad [][]byte) []byte {
+	d := cmac(s.mac, make([]byte, aes.BlockSize))
+	for _, a := range ad {
+		d = xorBytes(dbl(d), cmac(s.mac, a))
+	}
+
+	var t []byte
+	if len(pt) >= aes.BlockSize {
+		// XOR d into the last block of the plaintext.
+		t = append([]byte{}, pt...)
+		tail := t[len(t)-aes.BlockSize:]
+		copy(tail, xorBytes(tail, d))
+	} else {
+		padded := make([]byte, aes.BlockSize)
+		copy(padded, pt)
+		padded[len(pt)] = 0x80
+		t = xorBytes(dbl(d), padded)
+	}
+	return cmac(s.mac, t)
+}
+
+// crypt runs CTR from the synthetic IV with the top bit of its last two
+// 32-bit words cleared, so implementations can use 64-bit counters.
+func (s *SIV) crypt(v, data []byte) ([]byte, error) {
+	q := append([]byte{}, v...)
+	q[8] &= 0x7f
+	q[12] &= 0x7f
+	return CTRCrypt(data, s.ctr, q, BigEndian128)
+}
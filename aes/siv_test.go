@@ -0,0 +1,122 @@
+package aescbc
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func decodeSpaced(t *testing.T, s string) []byte {
+	t.Helper()
+	return decodeHex(t, strings.ReplaceAll(s, " ", ""))
+}
+
+// RFC 5297 appendix A.
+func TestSIVVectors(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		ad   []string
+		pt   string
+		ct   string
+	}{
+		{
+			name: "A.1 deterministic",
+			key:  "fffefdfc fbfaf9f8 f7f6f5f4 f3f2f1f0 f0f1f2f3 f4f5f6f7 f8f9fafb fcfdfeff",
+			ad:   []string{"10111213 14151617 18191a1b 1c1d1e1f 20212223 24252627"},
+			pt:   "11223344 55667788 99aabbcc ddee",
+			ct:   "85632d07 c6e8f37f 950acd32 0a2ecc93 40c02b96 90c4dc04 daef7f6a fe5c",
+		},
+		{
+			name: "A.2 nonce-based",
+			key:  "7f7e7d7c 7b7a7978 77767574 73727170 40414243 44454647 48494a4b 4c4d4e4f",
+			ad: []string{
+				"00112233 44556677 8899aabb ccddeeff deaddada deaddada ffeeddcc bbaa9988 77665544 33221100",
+				"10203040 50607080 90a0",
+				"09f91102 9d74e35b d84156c5 635688c0",
+			},
+			pt: "74686973 20697320 736f6d65 20706c61 696e7465 78742074 6f20656e 63727970 74207573 696e6720 5349562d 414553",
+			ct: "7bdb6e3b 432667eb 06f4d14b ff2fbd0f cb900f2f ddbe4043 26601965 c889bf17 dba77ceb 094fa663 b7a3f748 ba8af829 ea64ad54 4a272e9c 485b62a3 fd5c0d",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			siv, err := NewSIV(decodeSpaced(t, tt.key))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ad [][]byte
+			for _, a := range tt.ad {
+				ad = append(ad, decodeSpaced(t, a))
+			}
+			pt, want := decodeSpaced(t, tt.pt), decodeSpaced(t, tt.ct)
+
+			ct, err := siv.Seal(pt, ad...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(ct, want) {
+				t.Errorf("Seal() = %x, want %x", ct, want)
+			}
+
+			got, err := siv.Open(want, ad...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, pt) {
+				t.Errorf("Open() = %x, want %x", got, pt)
+			}
+
+			want[len(want)-1] ^= 1
+			if _, err := siv.Open(want, ad...); !errors.Is(err, ErrAuthentication) {
+				t.Errorf("Open() of modified ciphertext returned %v, want %v", err, ErrAuthentication)
+			}
+		})
+	}
+}
+
+// Encrypting under a repeated nonce only reveals whether two messages are
+// equal. With CTR or GCM it would also reveal the XOR of the plaintexts.
+func TestSIVRepeatedNonce(t *testing.T) {
+	siv, err := NewSIV(bytes.Repeat([]byte{0x42}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := []byte("fixed nonce 0001")
+
+	id := []byte("user-1234@example.com")
+	first, err := siv.Seal(id, nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := siv.Seal(id, nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Errorf("Seal() of the same message under the same nonce differs: %x and %x", first, second)
+	}
+
+	other := []byte("user-1235@example.com")
+	third, err := siv.Seal(other, nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(first[:16], third[:16]) {
+		t.Error("different messages got the same synthetic IV")
+	}
+	if bytes.Equal(xorBytes(first[16:], third[16:]), xorBytes(id, other)) {
+		t.Error("ciphertexts under a repeated nonce leak the XOR of the plaintexts")
+	}
+
+	for _, ct := range [][]byte{first, third} {
+		if _, err := siv.Open(ct, nonce); err != nil {
+			t.Errorf("Open(%x) returned %v", ct, err)
+		}
+		if _, err := siv.Open(ct, []byte("other nonce 0002")); !errors.Is(err, ErrAuthentication) {
+			t.Errorf("Open() under a different nonce returned %v, want %v", err, ErrAuthentication)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package aescbc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+// blockModeVector is one of the SP 800-38A known-answer tests. The vectors
+// have no padding, so only the first len(Plaintext) bytes of ciphertext are
+// compared; the padding block Encrypt and ECBEncrypt add comes after them.
+type blockModeVector struct {
+	Name       string `json:"name"`
+	Mode       string `json:"mode"`
+	Key        string `json:"key"`
+	IV         string `json:"iv"`
+	Plaintext  string `json:"plaintext"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+type gcmVector struct {
+	Name       string `json:"name"`
+	Key        string `json:"key"`
+	IV         string `json:"iv"`
+	Plaintext  string `json:"plaintext"`
+	AAD        string `json:"aad"`
+	Ciphertext string `json:"ciphertext"`
+	Tag        string `json:"tag"`
+}
+
+func loadVectors(t *testing.T, name string, v any) {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatalf("parse %s: %v", name, err)
+	}
+}
+
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestSP800_38A(t *testing.T) {
+	var vectors []blockModeVector
+	loadVectors(t, "sp800_38a.json", &vectors)
+
+	for _, v := range vectors {
+		t.Run(v.Name, func(t *testing.T) {
+			key, iv := decodeHex(t, v.Key), decodeHex(t, v.IV)
+			pt, want := decodeHex(t, v.Plaintext), decodeHex(t, v.Ciphertext)
+
+			switch v.Mode {
+			case "ECB":
+				testECBVector(t, key, pt, want)
+			case "CBC":
+				testCBCVector(t, key, iv, pt, want)
+			case "CTR":
+				testCTRVector(t, key, iv, pt, want)
+			default:
+				t.Fatalf("unknown mode %q", v.Mode)
+			}
+		})
+	}
+}
+
+func testECBVector(t *testing.T, key, pt, want []byte) {
+	ct, err := ECBEncrypt(pt, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ct[:len(want)], want) {
+		t.Errorf("ECBEncrypt() = %x, want %x", ct[:len(want)], want)
+	}
+
+	got, err := ECBDecrypt(ct, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, pt) {
+		t.Errorf("ECBDecrypt() = %x, want %x", got, pt)
+	}
+}
+
+func testCBCVector(t *testing.T, key, iv, pt, want []byte) {
+	// Encrypt reads the IV from its random source.
+	ct, err := Encrypt(bytes.NewReader(iv), pt, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ct[:len(iv)], iv) {
+		t.Errorf("Encrypt() IV = %x, want %x", ct[:len(iv)], iv)
+	}
+	if got := ct[len(iv) : len(iv)+len(want)]; !bytes.Equal(got, want) {
+		t.Errorf("Encrypt() = %x, want %x", got, want)
+	}
+
+	got, err := Decrypt(ct, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, pt) {
+		t.Errorf("Decrypt() = %x, want %x", got, pt)
+	}
+}
+
+func testCTRVector(t *testing.T, key, iv, pt, want []byte) {
+	ct, err := CTRCrypt(pt, key, iv, BigEndian128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ct, want) {
+		t.Errorf("CTRCrypt() = %x, want %x", ct, want)
+	}
+
+	// Decrypting from an offset in the middle of a block must line up with
+	// the same keystream.
+	stream, err := NewCTR(key, iv, BigEndian128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const offset = 21
+	got := make([]byte, len(want)-offset)
+	stream.XORKeyStreamAt(got, want[offset:], offset)
+	if !bytes.Equal(got, pt[offset:]) {
+		t.Errorf("XORKeyStreamAt(%d) = %x, want %x", offset, got, pt[offset:])
+	}
+}
+
+func TestGCMVectors(t *testing.T) {
+	var vectors []gcmVector
+	loadVectors(t, "gcm.json", &vectors)
+
+	for _, v := range vectors {
+		t.Run(v.Name, func(t *testing.T) {
+			key, iv := decodeHex(t, v.Key), decodeHex(t, v.IV)
+			pt, aad := decodeHex(t, v.Plaintext), decodeHex(t, v.AAD)
+			want := append(decodeHex(t, v.Ciphertext), decodeHex(t, v.Tag)...)
+
+			aead, err := NewGCM(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ct := aead.Seal(nil, iv, pt, aad); !bytes.Equal(ct, want) {
+				t.Errorf("Seal() = %x, want %x", ct, want)
+			}
+
+			got, err := aead.Open(nil, iv, want, aad)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, pt) {
+				t.Errorf("Open() = %x, want %x", got, pt)
+			}
+
+			want[0] ^= 1
+			if _, err := aead.Open(nil, iv, want, aad); err == nil {
+				t.Error("Open() accepted a modified ciphertext")
+			}
+		})
+	}
+}
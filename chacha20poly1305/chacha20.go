@@ -0,0 +1,74 @@
+package chacha20poly1305
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// BlockSize is the size of one ChaCha20 keystream block.
+const BlockSize = 64
+
+// Block computes the ChaCha20 keystream block for the key, nonce and block
+// counter, as described in RFC 8439 section 2.3.
+func Block(key *[KeySize]byte, nonce *[NonceSize]byte, counter uint32) [BlockSize]byte {
+	var state [16]uint32
+	state[0], state[1], state[2], state[3] = 0x61707865, 0x3320646e, 0x79622d32, 0x6b206574
+	for i := 0; i < 8; i++ {
+		state[4+i] = binary.LittleEndian.Uint32(key[4*i:])
+	}
+	state[12] = counter
+	for i := 0; i < 3; i++ {
+		state[13+i] = binary.LittleEndian.Uint32(nonce[4*i:])
+	}
+
+	x := state
+	for i := 0; i < 10; i++ {
+		// Column rounds
+		quarterRound(&x, 0, 4, 8, 12)
+		quarterRound(&x, 1, 5, 9, 13)
+		quarterRound(&x, 2, 6, 10, 14)
+		quarterRound(&x, 3, 7, 11, 15)
+		// Diagonal rounds
+		quarterRound(&x, 0, 5, 10, 15)
+		quarterRound(&x, 1, 6, 11, 12)
+		quarterRound(&x, 2, 7, 8, 13)
+		quarterRound(&x, 3, 4, 9, 14)
+	}
+
+	var out [BlockSize]byte
+	for i := range x {
+		binary.LittleEndian.PutUint32(out[4*i:], x[i]+state[i])
+	}
+	return out
+}
+
+func quarterRound(x *[16]uint32, a, b, c, d int) {
+	x[a] += x[b]
+	x[d] = bits.RotateLeft32(x[d]^x[a], 16)
+	x[c] += x[d]
+	x[b] = bits.RotateLeft32(x[b]^x[c], 12)
+	x[a] += x[b]
+	x[d] = bits.RotateLeft32(x[d]^x[a], 8)
+	x[c] += x[d]
+	x[b] = bits.RotateLeft32(x[b]^x[c], 7)
+}
+
+// XORKeyStream XORs src with the ChaCha20 keystream starting at the given
+// block counter into dst. It panics if src needs more blocks than the 32-bit
+// counter has left, since the keystream would repeat.
+func XORKeyStream(dst, src []byte, key *[KeySize]byte, nonce *[NonceSize]byte, counter uint32) {
+	if len(dst) < len(src) {
+		panic("chacha20poly1305: output smaller than input")
+	}
+	if blocks := (uint64(len(src)) + BlockSize - 1) / BlockSize; uint64(counter)+blocks > 1<<32 {
+		panic("chacha20poly1305: block counter overflow")
+	}
+
+	for i := 0; i < len(src); i += BlockSize {
+		ks := Block(key, nonce, counter)
+		counter++
+		for j := 0; j < BlockSize && i+j < len(src); j++ {
+			dst[i+j] = src[i+j] ^ ks[j]
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Package chacha20poly1305 implements the ChaCha20 stream cipher, the
+// Poly1305 authenticator and the ChaCha20-Poly1305 AEAD from RFC 8439 in pure
+// Go, for peers without AES hardware.
+package chacha20poly1305
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	// KeySize is the size of a ChaCha20-Poly1305 key.
+	KeySize = 32
+	// NonceSize is the size of the nonce, which must never repeat under a key.
+	NonceSize = 12
+	// Overhead is the size of the tag appended to each ciphertext.
+	Overhead = TagSize
+)
+
+// ErrOpen is returned by Open when the ciphertext or additional data fails
+// authentication.
+var ErrOpen = errors.New("chacha20poly1305: message authentication failed")
+
+type chacha20poly1305 struct {
+	key [KeySize]byte
+}
+
+// New returns ChaCha20-Poly1305 as a cipher.AEAD keyed with a 32-byte key.
+func New(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("chacha20poly1305: key of length %d, need %d", len(key), KeySize)
+	}
+	aead := &chacha20poly1305{}
+	copy(aead.key[:], key)
+	return aead, nil
+}
+
+func (c *chacha20poly1305) NonceSize() int {
+	return NonceSize
+}
+
+func (c *chacha20poly1305) Overhead() int {
+	return Overhead
+}
+
+// Seal encrypts and authenticates plaintext and additionalData and appends
+// the ciphertext and tag to dst.
+func (c *chacha20poly1305) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	n := c.checkNonce(nonce)
+
+	ret, out := sliceForAppend(dst, len(plaintext)+Overhead)
+	ct := out[:len(plaintext)]
+	XORKeyStream(ct, plaintext, &c.key, n, 1)
+
+	tag := c.tag(n, ct, additionalData)
+	copy(out[len(plaintext):], tag[:])
+	return ret
+}
+
+// Open checks the tag and only then decrypts, appending the plaintext to dst.
+func (c *chacha20poly1305) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	n := c.checkNonce(nonce)
+	if len(ciphertext) < Overhead {
+		return nil, ErrOpen
+	}
+
+	ct := ciphertext[:len(ciphertext)-Overhead]
+	tag := c.tag(n, ct, additionalData)
+	if subtle.ConstantTimeCompare(tag[:], ciphertext[len(ct):]) != 1 {
+		return nil, ErrOpen
+	}
+
+	ret, out := sliceForAppend(dst, len(ct))
+	XORKeyStream(out, ct, &c.key, n, 1)
+	return ret, nil
+}
+
+func (c *chacha20poly1305) checkNonce(nonce []byte) *[NonceSize]byte {
+	if len(nonce) != NonceSize {
+		panic("chacha20poly1305: incorrect nonce length")
+	}
+	var n [NonceSize]byte
+	copy(n[:], nonce)
+	return &n
+}
+
+// tag computes the Poly1305 tag over the additional data and ciphertext, each
+// zero-padded to 16 bytes and followed by both lengths, with a one-time key
+// taken from keystream block 0 (RFC 8439 section 2.8).
+func (c *chacha20poly1305) tag(nonce *[NonceSize]byte, ct, additionalData []byte) [TagSize]byte {
+	block := Block(&c.key, nonce, 0)
+	var polyKey [32]byte
+	copy(polyKey[:], block[:32])
+
+	macData := make([]byte, 0, len(additionalData)+len(ct)+2*TagSize+16)
+	macData = append(macData, additionalData...)
+	macData = append(macData, make([]byte, pad16(len(additionalData)))...)
+	macData = append(macData, ct...)
+	macData = append(macData, make([]byte, pad16(len(ct)))...)
+	macData = binary.LittleEndian.AppendUint64(macData, uint64(len(additionalData)))
+	macData = binary.LittleEndian.AppendUint64(macData, uint64(len(ct)))
+	return Poly1305(macData, &polyKey)
+}
+
+func pad16(n int) int {
+	return (TagSize - n%TagSize) % TagSize
+}
+
+// sliceForAppend extends dst by n bytes, returning the whole slice and the
+// new bytes.
+func sliceForAppend(dst []byte, n int) (whole, tail []byte) {
+	if total := len(dst) + n; cap(dst) >= total {
+		whole = dst[:total]
+	} else {
+		whole = make([]byte, total)
+		copy(whole, dst)
+	}
+	return whole, whole[len(dst):]
+}
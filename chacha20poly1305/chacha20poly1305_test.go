@@ -0,0 +1,203 @@
+package chacha20poly1305
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const sunscreen = "Ladies and Gentlemen of the class of '99: If I could offer you only one tip for the future, sunscreen would be it."
+
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func testKey(t *testing.T, s string) *[KeySize]byte {
+	var key [KeySize]byte
+	copy(key[:], decodeHex(t, s))
+	return &key
+}
+
+func testNonce(t *testing.T, s string) *[NonceSize]byte {
+	var nonce [NonceSize]byte
+	copy(nonce[:], decodeHex(t, s))
+	return &nonce
+}
+
+// RFC 8439 section 2.3.2.
+func TestBlock(t *testing.T) {
+	key := testKey(t, "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f")
+	nonce := testNonce(t, "000000090000004a00000000")
+	want := decodeHex(t, "10f1e7e4d13b5915500fdd1fa32071c4c7d1f4c733c068030422aa9ac3d46c4e"+
+		"d2826446079faa0914c2d705d98b02a2b5129cd1de164eb9cbd083e8a2503c4e")
+
+	if got := Block(key, nonce, 1); !bytes.Equal(got[:], want) {
+		t.Errorf("Block() = %x, want %x", got, want)
+	}
+}
+
+// RFC 8439 section 2.4.2.
+func TestXORKeyStream(t *testing.T) {
+	key := testKey(t, "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f")
+	nonce := testNonce(t, "000000000000004a00000000")
+	want := decodeHex(t, "6e2e359a2568f98041ba0728dd0d6981e97e7aec1d4360c20a27afccfd9fae0b"+
+		"f91b65c5524733ab8f593dabcd62b3571639d624e65152ab8f530c359f0861d8"+
+		"07ca0dbf500d6a6156a38e088a22b65e52bc514d16ccf806818ce91ab7793736"+
+		"5af90bbf74a35be6b40b8eedf2785e42874d")
+
+	got := make([]byte, len(sunscreen))
+	XORKeyStream(got, []byte(sunscreen), key, nonce, 1)
+	if !bytes.Equal(got, want) {
+		t.Errorf("XORKeyStream() = %x, want %x", got, want)
+	}
+}
+
+// RFC 8439 section 2.5.2 and appendix A.3.
+func TestPoly1305(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		msg  string
+		tag  string
+	}{
+		{
+			name: "2.5.2",
+			key:  "85d6be7857556d337f4452fe42d506a80103808afb0db2fd4abff6af4149f51b",
+			msg:  hex.EncodeToString([]byte("Cryptographic Forum Research Group")),
+			tag:  "a8061dc1305136c6c22b8baf0c0127a9",
+		},
+		{
+			name: "A.3 #1",
+			key:  strings.Repeat("00", 32),
+			msg:  strings.Repeat("00", 64),
+			tag:  strings.Repeat("00", 16),
+		},
+		{
+			name: "A.3 #5",
+			key:  "02" + strings.Repeat("00", 31),
+			msg:  strings.Repeat("ff", 16),
+			tag:  "03" + strings.Repeat("00", 15),
+		},
+		{
+			name: "A.3 #6",
+			key:  "02" + strings.Repeat("00", 15) + strings.Repeat("ff", 16),
+			msg:  "02" + strings.Repeat("00", 15),
+			tag:  "03" + strings.Repeat("00", 15),
+		},
+		{
+			name: "A.3 #7",
+			key:  "01" + strings.Repeat("00", 31),
+			msg:  strings.Repeat("ff", 16) + "f0" + strings.Repeat("ff", 15) + "11" + strings.Repeat("00", 15),
+			tag:  "05" + strings.Repeat("00", 15),
+		},
+		{
+			name: "A.3 #8",
+			key:  "01" + strings.Repeat("00", 31),
+			msg:  strings.Repeat("ff", 16) + "fb" + strings.Repeat("fe", 15) + strings.Repeat("01", 16),
+			tag:  strings.Repeat("00", 16),
+		},
+		{
+			name: "A.3 #9",
+			key:  "02" + strings.Repeat("00", 31),
+			msg:  "fd" + strings.Repeat("ff", 15),
+			tag:  "fa" + strings.Repeat("ff", 15),
+		},
+		{
+			name: "A.3 #10",
+			key:  "0100000000000000" + "0400000000000000" + strings.Repeat("00", 16),
+			msg: "e33594d7505e43b90000000000000000" + "3394d7505e4379cd0100000000000000" +
+				strings.Repeat("00", 16) + "01" + strings.Repeat("00", 15),
+			tag: "14000000000000005500000000000000",
+		},
+		{
+			name: "A.3 #11",
+			key:  "0100000000000000" + "0400000000000000" + strings.Repeat("00", 16),
+			msg: "e33594d7505e43b90000000000000000" + "3394d7505e4379cd0100000000000000" +
+				strings.Repeat("00", 16),
+			tag: "13" + strings.Repeat("00", 15),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var key [32]byte
+			copy(key[:], decodeHex(t, tt.key))
+			want := decodeHex(t, tt.tag)
+
+			if got := Poly1305(decodeHex(t, tt.msg), &key); !bytes.Equal(got[:], want) {
+				t.Errorf("Poly1305() = %x, want %x", got, want)
+			}
+		})
+	}
+}
+
+// RFC 8439 section 2.8.2.
+func TestAEAD(t *testing.T) {
+	key := decodeHex(t, "808182838485868788898a8b8c8d8e8f909192939495969798999a9b9c9d9e9f")
+	nonce := decodeHex(t, "070000004041424344454647")
+	ad := decodeHex(t, "50515253c0c1c2c3c4c5c6c7")
+	want := decodeHex(t, "d31a8d34648e60db7b86afbc53ef7ec2a4aded51296e08fea9e2b5a736ee62d6"+
+		"3dbea45e8ca9671282fafb69da92728b1a71de0a9e060b2905d6a5b67ecd3b36"+
+		"92ddbd7f2d778b8c9803aee328091b58fab324e4fad675945585808b4831d7bc"+
+		"3ff4def08e4b7a9de576d26586cec64b6116"+
+		"1ae10b594f09e26a7e902ecbd0600691")
+
+	aead, err := New(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ct := aead.Seal(nil, nonce, []byte(sunscreen), ad); !bytes.Equal(ct, want) {
+		t.Errorf("Seal() = %x, want %x", ct, want)
+	}
+
+	pt, err := aead.Open(nil, nonce, want, ad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(pt) != sunscreen {
+		t.Errorf("Open() = %q, want %q", pt, sunscreen)
+	}
+
+	// Any change to the ciphertext, tag or additional data is rejected.
+	for _, i := range []int{0, len(want) - Overhead - 1, len(want) - 1} {
+		tampered := append([]byte{}, want...)
+		tampered[i] ^= 0x80
+		if _, err := aead.Open(nil, nonce, tampered, ad); !errors.Is(err, ErrOpen) {
+			t.Errorf("Open() with byte %d flipped returned %v, want %v", i, err, ErrOpen)
+		}
+	}
+	if _, err := aead.Open(nil, nonce, want, ad[1:]); !errors.Is(err, ErrOpen) {
+		t.Errorf("Open() with modified additional data returned %v, want %v", err, ErrOpen)
+	}
+	if _, err := aead.Open(nil, nonce, want[:Overhead-1], ad); !errors.Is(err, ErrOpen) {
+		t.Errorf("Open() of truncated ciphertext returned %v, want %v", err, ErrOpen)
+	}
+}
+
+func TestSealAppends(t *testing.T) {
+	aead, err := New(make([]byte, KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := make([]byte, NonceSize)
+
+	prefix := []byte("header")
+	ct := aead.Seal(append([]byte{}, prefix...), nonce, []byte("hello"), nil)
+	if !bytes.HasPrefix(ct, prefix) {
+		t.Fatalf("Seal() did not keep dst prefix: %x", ct)
+	}
+	pt, err := aead.Open(nil, nonce, ct[len(prefix):], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(pt) != "hello" {
+		t.Errorf("Open() = %q, want %q", pt, "hello")
+	}
+}
@@ -0,0 +1,81 @@
+package chacha20poly1305
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// TagSize is the size of a Poly1305 tag.
+const TagSize = 16
+
+// Poly1305 computes the one-time authenticator for msg described in RFC 8439
+// section 2.5. The key must never be used for more than one message.
+//
+// The accumulator h is kept in three 64-bit limbs and reduced modulo
+// 2^130 - 5 after every block, using 2^130 = 5 mod p.
+func Poly1305(msg []byte, key *[32]byte) [TagSize]byte {
+	r0 := binary.LittleEndian.Uint64(key[0:8]) & 0x0ffffffc0fffffff
+	r1 := binary.LittleEndian.Uint64(key[8:16]) & 0x0ffffffc0ffffffc
+	s0 := binary.LittleEndian.Uint64(key[16:24])
+	s1 := binary.LittleEndian.Uint64(key[24:32])
+
+	var h0, h1, h2 uint64
+	for len(msg) > 0 {
+		// Add the block with a 1 bit appended above its last byte.
+		var block [TagSize + 1]byte
+		n := copy(block[:TagSize], msg)
+		block[n] = 1
+		msg = msg[n:]
+
+		var c uint64
+		h0, c = bits.Add64(h0, binary.LittleEndian.Uint64(block[0:8]), 0)
+		h1, c = bits.Add64(h1, binary.LittleEndian.Uint64(block[8:16]), c)
+		h2 += c + uint64(block[16])
+
+		// Multiply by r. The clamped top bits of r0 and r1 keep h2*r and the
+		// sums of partial products from overflowing.
+		h0r0hi, h0r0lo := bits.Mul64(h0, r0)
+		h1r0hi, h1r0lo := bits.Mul64(h1, r0)
+		h0r1hi, h0r1lo := bits.Mul64(h0, r1)
+		h1r1hi, h1r1lo := bits.Mul64(h1, r1)
+		h2r0 := h2 * r0
+		h2r1 := h2 * r1
+
+		m1lo, c := bits.Add64(h1r0lo, h0r1lo, 0)
+		m1hi, _ := bits.Add64(h1r0hi, h0r1hi, c)
+		m2lo, c := bits.Add64(h2r0, h1r1lo, 0)
+		m2hi, _ := bits.Add64(0, h1r1hi, c)
+
+		t0 := h0r0lo
+		t1, c := bits.Add64(h0r0hi, m1lo, 0)
+		t2, c := bits.Add64(m1hi, m2lo, c)
+		t3, _ := bits.Add64(m2hi, h2r1, c)
+
+		// Reduce: everything above bit 130 is multiplied by 5 and added back
+		// in, as 4x (the bits in place) plus x (the bits shifted down by 2).
+		h0, h1, h2 = t0, t1, t2&3
+		cc0, cc1 := t2&^3, t3
+		h0, c = bits.Add64(h0, cc0, 0)
+		h1, c = bits.Add64(h1, cc1, c)
+		h2 += c
+		cc0, cc1 = cc0>>2|cc1<<62, cc1>>2
+		h0, c = bits.Add64(h0, cc0, 0)
+		h1, c = bits.Add64(h1, cc1, c)
+		h2 += c
+	}
+
+	// Subtract p if h >= p, without branching on h.
+	g0, b := bits.Sub64(h0, 0xfffffffffffffffb, 0)
+	g1, b := bits.Sub64(h1, 0xffffffffffffffff, b)
+	_, b = bits.Sub64(h2, 3, b)
+	mask := b - 1 // all ones if h >= p
+	h0 = h0&^mask | g0&mask
+	h1 = h1&^mask | g1&mask
+
+	var tag [TagSize]byte
+	h0, c := bits.Add64(h0, s0, 0)
+	h1, _ = bits.Add64(h1, s1, c)
+	binary.LittleEndian.PutUint64(tag[0:8], h0)
+	binary.LittleEndian.PutUint64(tag[8:16], h1)
+	return tag
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+
+	aescbc "github.com/jessesomerville/cryptopals_set5/aes"
+	"github.com/jessesomerville/cryptopals_set5/color"
+)
+
+// challenge11 checks the ECB/CBC detector against the encryption oracle.
+func challenge11() error {
+	const trials = 100
+	for i := 0; i < trials; i++ {
+		var used aescbc.Mode
+		oracle := func(input []byte) ([]byte, error) {
+			ct, mode, err := aescbc.EncryptionOracle(nil, input)
+			used = mode
+			return ct, err
+		}
+
+		detected, err := aescbc.DetectMode(oracle)
+		if err != nil {
+			return err
+		}
+		if detected != used {
+			return fmt.Errorf("trial %d: detected %s, oracle used %s", i, detected, used)
+		}
+	}
+	color.Green("[+] Detected the mode correctly in %d trials\n", trials)
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	aescbc "github.com/jessesomerville/cryptopals_set5/aes"
+	"github.com/jessesomerville/cryptopals_set5/color"
+)
+
+const challenge12Secret = "Um9sbGluJyBpbiBteSA1LjAKV2l0aCBteSByYWctdG9wIGRvd24gc28gbXkgaGFpciBjYW4gYmxvdwpUaGUgZ2lybGllcyBvbiBzdGFuZGJ5IHdhdmluZyBqdXN0IHRvIHNheSBoaQpEaWQgeW91IHN0b3A/IE5vLCBJIGp1c3QgZHJvdmUgYnkK"
+
+// challenge12 recovers the secret appended by an ECB oracle one byte at a
+// time, comparing one oracle call per candidate with batched candidates.
+func challenge12() error {
+	return byteAtATime(false)
+}
+
+// byteAtATime runs both attack strategies against an oracle with or without
+// a random prefix.
+func byteAtATime(randomPrefix bool) error {
+	secret, err := base64.StdEncoding.DecodeString(challenge12Secret)
+	if err != nil {
+		return err
+	}
+
+	oracle, err := aescbc.NewECBSuffixOracle(nil, secret, randomPrefix)
+	if err != nil {
+		return err
+	}
+
+	for _, batch := range []bool{false, true} {
+		result, err := aescbc.ByteAtATimeECB(oracle.Encrypt, aescbc.ByteAtATimeOptions{Batch: batch})
+		if err != nil {
+			return err
+		}
+		if string(result.Secret) != string(secret) {
+			return fmt.Errorf("recovered %q, want %q", result.Secret, secret)
+		}
+		fmt.Printf("[+] Block size %d, prefix %d bytes, batch=%t: %d oracle calls\n",
+			result.BlockSize, result.PrefixLen, batch, result.OracleCalls)
+	}
+	color.Green("[+] Secret:\n%s", secret)
+	return nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+
+	aescbc "github.com/jessesomerville/cryptopals_set5/aes"
+	"github.com/jessesomerville/cryptopals_set5/color"
+)
+
+// challenge13 forges an admin profile from ECB encrypted profiles by cutting
+// and pasting ciphertext blocks.
+func challenge13() error {
+	oracle, err := aescbc.NewProfileOracle(nil)
+	if err != nil {
+		return err
+	}
+
+	forged, err := aescbc.ECBCutAndPaste(oracle.Encrypt)
+	if err != nil {
+		return err
+	}
+	profile, err := oracle.Decrypt(forged)
+	if err != nil {
+		return err
+	}
+	if profile.Role != "admin" {
+		return fmt.Errorf("forged profile has role %q", profile.Role)
+	}
+	color.Green("[+] Forged profile: %+v\n", *profile)
+	return nil
+}
@@ -0,0 +1,7 @@
+package main
+
+// challenge14 repeats the byte-at-a-time attack against an oracle that puts a
+// random-length random prefix in front of the attacker's input.
+func challenge14() error {
+	return byteAtATime(true)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+
+	aescbc "github.com/jessesomerville/cryptopals_set5/aes"
+	"github.com/jessesomerville/cryptopals_set5/color"
+)
+
+// challenge16 forges an admin cookie by flipping ciphertext bits, then shows
+// the same attack failing against the authenticated CBC mode.
+func challenge16() error {
+	oracle, err := aescbc.NewCookieOracle(nil)
+	if err != nil {
+		return err
+	}
+
+	forged, err := aescbc.CBCBitflipAttack(oracle.Encrypt)
+	if err != nil {
+		return err
+	}
+	admin, err := oracle.IsAdmin(forged)
+	if err != nil {
+		return err
+	}
+	if !admin {
+		return fmt.Errorf("forged cookie is not an admin cookie")
+	}
+	color.Green("[+] Forged an admin cookie\n")
+
+	oracle.Authenticated = true
+	forged, err = aescbc.CBCBitflipAttack(oracle.Encrypt)
+	if err != nil {
+		return err
+	}
+	admin, err = oracle.IsAdmin(forged)
+	if admin {
+		return fmt.Errorf("forged an admin cookie against authenticated CBC")
+	}
+	color.Green("[+] Authenticated CBC rejected the forged cookie: %v\n", err)
+	return nil
+}
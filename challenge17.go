@@ -0,0 +1,71 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+
+	aescbc "github.com/jessesomerville/cryptopals_set5/aes"
+	"github.com/jessesomerville/cryptopals_set5/color"
+)
+
+var challenge17Strings = []string{
+	"MDAwMDAwTm93IHRoYXQgdGhlIHBhcnR5IGlzIGp1bXBpbmc=",
+	"MDAwMDAxV2l0aCB0aGUgYmFzcyBraWNrZWQgaW4gYW5kIHRoZSBWZWdhJ3MgYXJlIHB1bXBpbic=",
+	"MDAwMDAyUXVpY2sgdG8gdGhlIHBvaW50LCB0byB0aGUgcG9pbnQsIG5vIGZha2luZw==",
+	"MDAwMDAzQ29va2luZyBNQydzIGxpa2UgYSBwb3VuZCBvZiBiYWNvbg==",
+	"MDAwMDA0QnVybmluZyAnZW0sIGlmIHlvdSBhaW4ndCBxdWljayBhbmQgbmltYmxl",
+	"MDAwMDA1SSBnbyBjcmF6eSB3aGVuIEkgaGVhciBhIGN5bWJhbA==",
+	"MDAwMDA2QW5kIGEgaGlnaCBoYXQgd2l0aCBhIHNvdXBlZCB1cCB0ZW1wbw==",
+	"MDAwMDA3SSdtIG9uIGEgcm9sbCwgaXQncyB0aW1lIHRvIGdvIHNvbG8=",
+	"MDAwMDA4b2xsaW4nIGluIG15IGZpdmUgcG9pbnQgb2g=",
+	"MDAwMDA5aXRoIG15IHJhZy10b3AgZG93biBzbyBteSBoYWlyIGNhbiBibG93",
+}
+
+// challenge17 encrypts a random string with the padding oracle's key and
+// decrypts it again using only the oracle.
+func challenge17() error {
+	oracle, err := aescbc.NewPaddingOracle(nil)
+	if err != nil {
+		return err
+	}
+
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(len(challenge17Strings))))
+	if err != nil {
+		return err
+	}
+	secret, err := base64.StdEncoding.DecodeString(challenge17Strings[i.Int64()])
+	if err != nil {
+		return err
+	}
+
+	ct, err := oracle.Encrypt(secret)
+	if err != nil {
+		return err
+	}
+
+	pt, err := aescbc.PaddingOracleAttack(ct, oracle.ValidPadding)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("[+] Decrypted with %d oracle queries\n", oracle.Queries)
+
+	if string(pt) != string(secret) {
+		return fmt.Errorf("decrypted %q, want %q", pt, secret)
+	}
+	color.Green("[+] Plaintext: %s\n", pt)
+
+	// With encrypt-then-MAC every forged ciphertext is rejected before the
+	// padding is checked, so the oracle has nothing to leak.
+	oracle.Authenticated = true
+	ct, err = oracle.Encrypt(secret)
+	if err != nil {
+		return err
+	}
+	if _, err := aescbc.PaddingOracleAttack(ct, oracle.ValidPadding); err == nil {
+		return fmt.Errorf("padding oracle attack succeeded against authenticated CBC")
+	}
+	color.Green("[+] Attack failed against authenticated CBC\n")
+	return nil
+}
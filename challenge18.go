@@ -0,0 +1,37 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	aescbc "github.com/jessesomerville/cryptopals_set5/aes"
+	"github.com/jessesomerville/cryptopals_set5/color"
+)
+
+// challenge18 decrypts the CTR ciphertext from challenge 18, which uses the
+// key "YELLOW SUBMARINE" and nonce 0.
+func challenge18() error {
+	ct, err := base64.StdEncoding.DecodeString("L77na/nrFsKvynd6HzOoG7GHTLXsTVu9qvY/2syLXzhPweyyMTJULu/6/kXX0KSvoOLSFQ==")
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, aescbc.LittleEndian64.NonceSize())
+	pt, err := aescbc.CTRCrypt(ct, []byte("YELLOW SUBMARINE"), nonce, aescbc.LittleEndian64)
+	if err != nil {
+		return err
+	}
+	color.Green("[+] Plaintext: %s\n", pt)
+
+	// The keystream can be read from any offset.
+	stream, err := aescbc.NewCTR([]byte("YELLOW SUBMARINE"), nonce, aescbc.LittleEndian64)
+	if err != nil {
+		return err
+	}
+	tail := make([]byte, len(ct)-20)
+	stream.XORKeyStreamAt(tail, ct[20:], 20)
+	if string(tail) != string(pt[20:]) {
+		return fmt.Errorf("seeked decryption %q does not match %q", tail, pt[20:])
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	aescbc "github.com/jessesomerville/cryptopals_set5/aes"
+	"github.com/jessesomerville/cryptopals_set5/randutil"
+)
+
+var challenge19Strings = []string{
+	"SSBoYXZlIG1ldCB0aGVtIGF0IGNsb3NlIG9mIGRheQ==",
+	"Q29taW5nIHdpdGggdml2aWQgZmFjZXM=",
+	"RnJvbSBjb3VudGVyIG9yIGRlc2sgYW1vbmcgZ3JleQ==",
+	"RWlnaHRlZW50aC1jZW50dXJ5IGhvdXNlcy4=",
+	"SSBoYXZlIHBhc3NlZCB3aXRoIGEgbm9kIG9mIHRoZSBoZWFk",
+	"T3IgcG9saXRlIG1lYW5pbmdsZXNzIHdvcmRzLA==",
+	"T3IgaGF2ZSBsaW5nZXJlZCBhd2hpbGUgYW5kIHNhaWQ=",
+	"UG9saXRlIG1lYW5pbmdsZXNzIHdvcmRzLA==",
+	"QW5kIHRob3VnaHQgYmVmb3JlIEkgaGFkIGRvbmU=",
+	"T2YgYSBtb2NraW5nIHRhbGUgb3IgYSBnaWJl",
+	"VG8gcGxlYXNlIGEgY29tcGFuaW9u",
+	"QXJvdW5kIHRoZSBmaXJlIGF0IHRoZSBjbHViLA==",
+	"QmVpbmcgY2VydGFpbiB0aGF0IHRoZXkgYW5kIEk=",
+	"QnV0IGxpdmVkIHdoZXJlIG1vdGxleSBpcyB3b3JuOg==",
+	"QWxsIGNoYW5nZWQsIGNoYW5nZWQgdXR0ZXJseTo=",
+	"QSB0ZXJyaWJsZSBiZWF1dHkgaXMgYm9ybi4=",
+	"VGhhdCB3b21hbidzIGRheXMgd2VyZSBzcGVudA==",
+	"SW4gaWdub3JhbnQgZ29vZCB3aWxsLA==",
+	"SGVyIG5pZ2h0cyBpbiBhcmd1bWVudA==",
+	"VW50aWwgaGVyIHZvaWNlIGdyZXcgc2hyaWxsLg==",
+	"V2hhdCB2b2ljZSBtb3JlIHN3ZWV0IHRoYW4gaGVycw==",
+	"V2hlbiB5b3VuZyBhbmQgYmVhdXRpZnVsLA==",
+	"U2hlIHJvZGUgdG8gaGFycmllcnM/",
+	"VGhpcyBtYW4gaGFkIGtlcHQgYSBzY2hvb2w=",
+	"QW5kIHJvZGUgb3VyIHdpbmdlZCBob3JzZS4=",
+	"VGhpcyBvdGhlciBoaXMgaGVscGVyIGFuZCBmcmllbmQ=",
+	"V2FzIGNvbWluZyBpbnRvIGhpcyBmb3JjZTs=",
+	"SGUgbWlnaHQgaGF2ZSB3b24gZmFtZSBpbiB0aGUgZW5kLA==",
+	"U28gc2Vuc2l0aXZlIGhpcyBuYXR1cmUgc2VlbWVkLA==",
+	"U28gZGFyaW5nIGFuZCBzd2VldCBoaXMgdGhvdWdodC4=",
+	"VGhpcyBvdGhlciBtYW4gSSBoYWQgZHJlYW1lZA==",
+	"QSBkcnVua2VuLCB2YWluLWdsb3Jpb3VzIGxvdXQu",
+	"SGUgaGFkIGRvbmUgbW9zdCBiaXR0ZXIgd3Jvbmc=",
+	"VG8gc29tZSB3aG8gYXJlIG5lYXIgbXkgaGVhcnQs",
+	"WWV0IEkgbnVtYmVyIGhpbSBpbiB0aGUgc29uZzs=",
+	"SGUsIHRvbywgaGFzIHJlc2lnbmVkIGhpcyBwYXJ0",
+	"SW4gdGhlIGNhc3VhbCBjb21lZHk7",
+	"SGUsIHRvbywgaGFzIGJlZW4gY2hhbmdlZCBpbiBoaXMgdHVybiw=",
+	"VHJhbnNmb3JtZWQgdXR0ZXJseTo=",
+	"QSB0ZXJyaWJsZSBiZWF1dHkgaXMgYm9ybi4=",
+}
+
+// challenge19 encrypts each string with CTR under the same key and nonce and
+// recovers the keystream statistically. If interactive is set, bytes can then
+// be corrected by hand with lines of the form "<line> <column> <char>", where
+// char is everything after the second space (so it may itself be a space) or a
+// hex byte such as 0x20.
+func challenge19(interactive bool) error {
+	key := make([]byte, 16)
+	if err := randutil.Read(nil, key); err != nil {
+		return err
+	}
+	nonce := make([]byte, aescbc.LittleEndian64.NonceSize())
+
+	cts := make([][]byte, len(challenge19Strings))
+	for i, s := range challenge19Strings {
+		pt, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return err
+		}
+		cts[i], err = aescbc.CTRCrypt(pt, key, nonce, aescbc.LittleEndian64)
+		if err != nil {
+			return err
+		}
+	}
+
+	keystream := aescbc.BreakFixedNonceCTR(cts)
+	printDecryptions(cts, keystream)
+	if !interactive {
+		return nil
+	}
+
+	fmt.Println("[+] Correct a byte with \"<line> <column> <char|0xNN>\", or an empty line to finish")
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		if scanner.Text() == "" {
+			return nil
+		}
+
+		line, col, char, err := parseCorrection(scanner.Text())
+		if err != nil {
+			fmt.Printf("[!] Expected \"<line> <column> <char|0xNN>\": %v\n", err)
+			continue
+		}
+		if line < 0 || line >= len(cts) || col < 0 || col >= len(cts[line]) {
+			fmt.Println("[!] Position out of range")
+			continue
+		}
+
+		aescbc.CorrectKeystream(keystream, cts[line], col, char)
+		printDecryptions(cts, keystream)
+	}
+	if err := scanner.Err(); err != nil && err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// parseCorrection parses "<line> <column> <char>". The two numbers are split
+// on single spaces and the rest of the input is the character, so a space can
+// be entered as a correction. A rest of the form 0xNN is read as a hex byte.
+func parseCorrection(text string) (line, col int, char byte, err error) {
+	fields := strings.SplitN(text, " ", 3)
+	if len(fields) != 3 {
+		return 0, 0, 0, errors.New("missing field")
+	}
+	if line, err = strconv.Atoi(fields[0]); err != nil {
+		return 0, 0, 0, err
+	}
+	if col, err = strconv.Atoi(fields[1]); err != nil {
+		return 0, 0, 0, err
+	}
+
+	switch rest := fields[2]; {
+	case len(rest) == 1:
+		return line, col, rest[0], nil
+	case len(rest) == 4 && strings.HasPrefix(rest, "0x"):
+		b, err := strconv.ParseUint(rest[2:], 16, 8)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		return line, col, byte(b), nil
+	default:
+		return 0, 0, 0, fmt.Errorf("%q is not a single character", rest)
+	}
+}
+
+func printDecryptions(cts [][]byte, keystream []byte) {
+	for i, ct := range cts {
+		fmt.Printf("%2d: %s\n", i, aescbc.XORKeystream(ct, keystream))
+	}
+}
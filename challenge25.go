@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	aescbc "github.com/jessesomerville/cryptopals_set5/aes"
+	"github.com/jessesomerville/cryptopals_set5/color"
+)
+
+// challenge25 recovers CTR encrypted data through the edit function exposed
+// by seekable encrypted storage.
+func challenge25() error {
+	oracle, err := aescbc.NewEditOracle(nil)
+	if err != nil {
+		return err
+	}
+
+	secret := []byte("I'm back and I'm ringin' the bell\nA rockin' on the mike while the fly girls yell\n")
+	ct := oracle.Encrypt(secret)
+
+	// A legitimate edit only changes the bytes it covers.
+	edited, err := oracle.Edit(ct, 9, []byte("BACK"))
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(edited[:9], ct[:9]) || !bytes.Equal(edited[13:], ct[13:]) {
+		return fmt.Errorf("edit changed bytes outside the edited range")
+	}
+
+	pt, err := aescbc.RecoverWithEdit(ct, oracle.Edit)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(pt, secret) {
+		return fmt.Errorf("recovered %q, want %q", pt, secret)
+	}
+	color.Green("[+] Recovered plaintext: %s", pt)
+	return nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+
+	aescbc "github.com/jessesomerville/cryptopals_set5/aes"
+	"github.com/jessesomerville/cryptopals_set5/color"
+)
+
+// challenge26 forges an admin cookie by flipping bits in a CTR ciphertext.
+func challenge26() error {
+	oracle, err := aescbc.NewCTRCookieOracle(nil)
+	if err != nil {
+		return err
+	}
+
+	forged, err := aescbc.CTRBitflipAttack(oracle.Encrypt)
+	if err != nil {
+		return err
+	}
+	admin, err := oracle.IsAdmin(forged)
+	if err != nil {
+		return err
+	}
+	if !admin {
+		return fmt.Errorf("forged cookie is not an admin cookie")
+	}
+	color.Green("[+] Forged an admin cookie\n")
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+
+	aescbc "github.com/jessesomerville/cryptopals_set5/aes"
+	"github.com/jessesomerville/cryptopals_set5/color"
+)
+
+// challenge27 recovers the key from a receiver that uses it as the IV and
+// echoes the plaintext of messages it rejects.
+func challenge27() error {
+	receiver, err := aescbc.NewKeyIVReceiver(nil)
+	if err != nil {
+		return err
+	}
+
+	secret := []byte("comment1=cooking%20MCs;userdata=hello;comment2=%20like%20a%20pound%20of%20bacon")
+	ct, err := receiver.Encrypt(secret)
+	if err != nil {
+		return err
+	}
+
+	key, err := aescbc.RecoverKeyIV(ct, receiver.Receive)
+	if err != nil {
+		return err
+	}
+
+	pt, err := aescbc.DecryptKeyIV(ct, key)
+	if err != nil {
+		return err
+	}
+	if string(pt) != string(secret) {
+		return fmt.Errorf("recovered key %x decrypts to %q", key, pt)
+	}
+	color.Green("[+] Recovered key: %x\n", key)
+	return nil
+}
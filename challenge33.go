@@ -5,12 +5,12 @@ import dh "github.com/jessesomerville/cryptopals_set5/diffie_hellman"
 func challenge33() []byte {
 	group := dh.GetGroup()
 
-	keypairA, err := dh.GenerateKeyPair(group)
+	keypairA, err := dh.GenerateKeyPair(nil, group)
 	if err != nil {
 		panic(err)
 	}
 
-	keypairB, err := dh.GenerateKeyPair(group)
+	keypairB, err := dh.GenerateKeyPair(nil, group)
 	if err != nil {
 		panic(err)
 	}
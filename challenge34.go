@@ -11,15 +11,15 @@ import (
 )
 
 func challenge34() error {
-	clientA, err := socketclient.NewDHSocketClient("ClientA")
+	clientA, err := socketclient.NewDHSocketClient("ClientA", nil)
 	if err != nil {
 		log.Fatal(err)
 	}
-	clientB, err := socketclient.NewDHSocketClient("ClientB")
+	clientB, err := socketclient.NewDHSocketClient("ClientB", nil)
 	if err != nil {
 		log.Fatal(err)
 	}
-	MITM, err := socketclient.NewMITMSocketClient("MITM")
+	MITM, err := socketclient.NewMITMSocketClient("MITM", nil)
 	if err != nil {
 		log.Fatal(err)
 	}
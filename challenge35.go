@@ -1,8 +1,10 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"log"
+	"math/big"
 	"time"
 
 	"github.com/jessesomerville/cryptopals_set5/color"
@@ -10,20 +12,47 @@ import (
 	socketclient "github.com/jessesomerville/cryptopals_set5/socket_client"
 )
 
-// Incomplete
+// challenge35 repeats the MITM attack with a malicious generator instead of
+// swapped public keys. For each of g = 1, p and p-1 the MITM forwards the group
+// with g to the responder and sends g as the initiator's public key, which
+// leaves both session keys determined by the responder's public key.
 func challenge35() error {
-	clientA, err := socketclient.NewDHSocketClient("ClientA")
+	p := dh.GetGroup().P
+	generators := []struct {
+		name string
+		g    *big.Int
+	}{
+		{"g = 1", big.NewInt(1)},
+		{"g = p", new(big.Int).Set(p)},
+		{"g = p - 1", new(big.Int).Sub(p, big.NewInt(1))},
+	}
+	for _, gen := range generators {
+		fmt.Printf("[+] Injecting %s\n", gen.name)
+		if err := injectGenerator(gen.g); err != nil {
+			return fmt.Errorf("%s: %v", gen.name, err)
+		}
+	}
+	return nil
+}
+
+// injectGenerator runs one handshake and message exchange through a MITM that
+// injects g. The connections are left open, since the listeners exit the
+// process when a peer disconnects.
+func injectGenerator(g *big.Int) error {
+	clientA, err := socketclient.NewDHSocketClient("ClientA", nil)
 	if err != nil {
 		log.Fatal(err)
 	}
-	clientB, err := socketclient.NewDHSocketClient("ClientB")
+	clientB, err := socketclient.NewDHSocketClient("ClientB", nil)
 	if err != nil {
 		log.Fatal(err)
 	}
-	mitm, err := socketclient.NewMITMSocketClient("MITM")
+	mitm, err := socketclient.NewMITMSocketClient("MITM", nil)
 	if err != nil {
 		log.Fatal(err)
 	}
+	mitm.InjectedGroup = &dh.DHGroup{P: dh.GetGroup().P, G: g}
+	mitm.InjectedPubKey = g
 
 	go mitm.Listen()    // Start MITM listener
 	go clientA.Listen() // Start Peer listener
@@ -31,13 +60,17 @@ func challenge35() error {
 	fmt.Println("[+] Waiting 1 second to attempt to connect")
 	time.Sleep(time.Second)
 
-	// Have ClientB connect to ClientA through MITM
+	// Have ClientB connect to the MITM
 	if err := clientB.Connect(mitm.Port); err != nil {
 		return err
 	}
-	defer clientB.Conn.Close()
 
-	// Have ClientB initiate handshake with ClientA through MITM
+	// Have the MITM connect to ClientA
+	if err := mitm.Connect(clientA.Port); err != nil {
+		return err
+	}
+
+	// Have ClientB initiate handshake with ClientA through the MITM
 	if err := clientB.DoHandshake(mitm.Port); err != nil {
 		return err
 	}
@@ -46,10 +79,12 @@ func challenge35() error {
 	clientA.SessionKey = dh.ComputeSessionKey(clientA.KeyPair, clientA.PeerPubKey)[:16]
 	clientB.SessionKey = dh.ComputeSessionKey(clientB.KeyPair, clientB.PeerPubKey)[:16]
 
-	fmt.Printf("[+] Finished handshake\n")
+	// The MITM predicts both of them from ClientA's public key
+	if err := mitm.ComputeInjectedSessionKeys(); err != nil {
+		return err
+	}
 
-	color.Green("ClientA Key: %v", clientA.SessionKey)
-	color.Green("ClientB Key: %v", clientB.SessionKey)
+	fmt.Printf("[+] Finished handshake\n")
 
 	msg := socketclient.Message{
 		Type: 4,
@@ -63,15 +98,11 @@ func challenge35() error {
 	if err != nil {
 		return err
 	}
-	color.Blue("[+] %s received: %s\n\n", clientB.ID, string(respMsg.Data))
+	color.Blue("[+] %s received: %s\n", clientB.ID, string(respMsg.Data))
 
-	exitMsg := socketclient.Message{
-		Type: 99,
-		Data: []byte{},
+	if !bytes.Equal(mitm.InitiatorSessionKey, clientB.SessionKey) || !bytes.Equal(mitm.ResponderSessionKey, clientA.SessionKey) {
+		return fmt.Errorf("MITM session keys do not match the clients'")
 	}
-	if err := clientB.SendMessage(clientB.Conn, exitMsg); err != nil {
-		return err
-	}
-
+	color.Green("[+] MITM recovered both session keys\n\n")
 	return nil
 }
@@ -11,12 +11,12 @@ import (
 var one = big.NewInt(1)
 
 func challenge39() {
-	p, err := rsa.RandPrime()
+	p, err := rsa.RandPrime(nil)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	q, err := rsa.RandPrime()
+	q, err := rsa.RandPrime(nil)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/hmac"
+	"fmt"
+	"strconv"
+	"strings"
+
+	aescbc "github.com/jessesomerville/cryptopals_set5/aes"
+	"github.com/jessesomerville/cryptopals_set5/color"
+	"github.com/jessesomerville/cryptopals_set5/randutil"
+)
+
+// bank is a toy transaction API. The web client and the API server share key,
+// and the client only signs requests for the logged in account.
+type bank struct {
+	key      []byte
+	balances map[string]int
+}
+
+type transfer struct {
+	to     string
+	amount int
+}
+
+func newBank() (*bank, error) {
+	key := make([]byte, 16)
+	if err := randutil.Read(nil, key); err != nil {
+		return nil, err
+	}
+	return &bank{
+		key:      key,
+		balances: map[string]int{"1": 1000000, "2": 0, "3": 0},
+	}, nil
+}
+
+// signTransfer is the client for the first API version, which lets the client
+// pick the IV. Requests are message || IV || MAC.
+func (b *bank) signTransfer(from, to string, amount int) ([]byte, error) {
+	msg := []byte(fmt.Sprintf("from=%s&to=%s&amount=%d", from, to, amount))
+	iv := make([]byte, aes.BlockSize)
+	if err := randutil.Read(nil, iv); err != nil {
+		return nil, err
+	}
+	mac, err := aescbc.CBCMAC(msg, b.key, iv)
+	if err != nil {
+		return nil, err
+	}
+	return append(append(msg, iv...), mac...), nil
+}
+
+// processTransfer verifies and applies a request from the first API version.
+func (b *bank) processTransfer(req []byte) error {
+	if len(req) < 2*aes.BlockSize {
+		return fmt.Errorf("request too short")
+	}
+	msg := req[:len(req)-2*aes.BlockSize]
+	iv := req[len(req)-2*aes.BlockSize : len(req)-aes.BlockSize]
+	mac := req[len(req)-aes.BlockSize:]
+
+	want, err := aescbc.CBCMAC(msg, b.key, iv)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(mac, want) {
+		return fmt.Errorf("invalid MAC")
+	}
+
+	fields := map[string]string{}
+	for _, field := range strings.Split(string(msg), "&") {
+		k, v, _ := strings.Cut(field, "=")
+		fields[k] = v
+	}
+	amount, err := strconv.Atoi(fields["amount"])
+	if err != nil {
+		return fmt.Errorf("invalid amount: %v", err)
+	}
+	b.move(fields["from"], fields["to"], amount)
+	return nil
+}
+
+// signTransactions is the client for the second API version, which uses a
+// fixed zero IV and batches transfers. Requests are message || MAC.
+func (b *bank) signTransactions(from string, txs []transfer) ([]byte, error) {
+	var list []string
+	for _, tx := range txs {
+		list = append(list, fmt.Sprintf("%s:%d", tx.to, tx.amount))
+	}
+	msg := []byte(fmt.Sprintf("from=%s&tx_list=%s", from, strings.Join(list, ";")))
+
+	mac, err := aescbc.CBCMAC(msg, b.key, make([]byte, aes.BlockSize))
+	if err != nil {
+		return nil, err
+	}
+	return append(msg, mac...), nil
+}
+
+// processTransactions verifies and applies a request from the second API
+// version. Malformed transactions in the list are skipped.
+func (b *bank) processTransactions(req []byte) error {
+	if len(req) < aes.BlockSize {
+		return fmt.Errorf("request too short")
+	}
+	msg := req[:len(req)-aes.BlockSize]
+	mac := req[len(req)-aes.BlockSize:]
+
+	want, err := aescbc.CBCMAC(msg, b.key, make([]byte, aes.BlockSize))
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(mac, want) {
+		return fmt.Errorf("invalid MAC")
+	}
+
+	fromField, listField, _ := bytes.Cut(msg, []byte("&tx_list="))
+	from := strings.TrimPrefix(string(fromField), "from=")
+	for _, tx := range strings.Split(string(listField), ";") {
+		to, amountStr, ok := strings.Cut(tx, ":")
+		amount, err := strconv.Atoi(amountStr)
+		if !ok || err != nil {
+			continue
+		}
+		b.move(from, to, amount)
+	}
+	return nil
+}
+
+func (b *bank) move(from, to string, amount int) {
+	b.balances[from] -= amount
+	b.balances[to] += amount
+}
+
+// forgeTransfer turns a request the attacker signed for their own account
+// into one from the victim's account. Only the first block changes, and since
+// the attacker controls the IV they can cancel the change out:
+// IV' = IV ^ P1 ^ P1'.
+func forgeTransfer(req []byte, attacker, victim string) ([]byte, error) {
+	forged := append([]byte{}, req...)
+	msgLen := len(req) - 2*aes.BlockSize
+	if msgLen < aes.BlockSize {
+		return nil, fmt.Errorf("message shorter than a block")
+	}
+
+	first := forged[:aes.BlockSize]
+	replaced := bytes.Replace(first, []byte("from="+attacker), []byte("from="+victim), 1)
+	if len(replaced) != aes.BlockSize || bytes.Equal(replaced, first) {
+		return nil, fmt.Errorf("cannot rewrite sender in the first block")
+	}
+
+	iv := forged[msgLen : msgLen+aes.BlockSize]
+	for i := range iv {
+		iv[i] ^= first[i] ^ replaced[i]
+	}
+	copy(first, replaced)
+	return forged, nil
+}
+
+// extendTransactions appends the attacker's own signed transaction list to a
+// request captured from the victim. CBC-MAC(m || pad || (m'1 ^ t) || m'rest)
+// equals CBC-MAC(m') when t is the MAC of m, so the attacker's MAC is valid
+// for the combined message.
+func extendTransactions(victimReq, attackerReq []byte) []byte {
+	victimMsg := victimReq[:len(victimReq)-aes.BlockSize]
+	victimMAC := victimReq[len(victimReq)-aes.BlockSize:]
+	attackerMsg := attackerReq[:len(attackerReq)-aes.BlockSize]
+	attackerMAC := attackerReq[len(attackerReq)-aes.BlockSize:]
+
+	padLen := aes.BlockSize - len(victimMsg)%aes.BlockSize
+	forged := append([]byte{}, victimMsg...)
+	forged = append(forged, bytes.Repeat([]byte{byte(padLen)}, padLen)...)
+
+	glue := append([]byte{}, attackerMsg[:aes.BlockSize]...)
+	for i := range glue {
+		glue[i] ^= victimMAC[i]
+	}
+	forged = append(forged, glue...)
+	forged = append(forged, attackerMsg[aes.BlockSize:]...)
+	return append(forged, attackerMAC...)
+}
+
+func challenge49() error {
+	b, err := newBank()
+	if err != nil {
+		return err
+	}
+
+	// Attacker-controlled IV: the attacker (account 2) signs a transfer to
+	// themselves and rewrites it to come from the victim (account 1).
+	req, err := b.signTransfer("2", "2", 1000000)
+	if err != nil {
+		return err
+	}
+	forged, err := forgeTransfer(req, "2", "1")
+	if err != nil {
+		return err
+	}
+	if err := b.processTransfer(forged); err != nil {
+		return err
+	}
+	color.Green("[+] Forged transfer accepted, balances: %v\n", b.balances)
+
+	// Fixed IV: the victim's signed batch is extended with a transaction the
+	// attacker signed for their own account.
+	victimReq, err := b.signTransactions("1", []transfer{{to: "3", amount: 10}})
+	if err != nil {
+		return err
+	}
+	attackerReq, err := b.signTransactions("2", []transfer{{to: "2", amount: 1}, {to: "2", amount: 1000000}})
+	if err != nil {
+		return err
+	}
+
+	before := b.balances["2"]
+	if err := b.processTransactions(extendTransactions(victimReq, attackerReq)); err != nil {
+		return err
+	}
+	if b.balances["2"]-before < 1000000 {
+		return fmt.Errorf("length extension did not move the funds")
+	}
+	color.Green("[+] Extended transaction list accepted, balances: %v\n", b.balances)
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	aescbc "github.com/jessesomerville/cryptopals_set5/aes"
+	"github.com/jessesomerville/cryptopals_set5/color"
+)
+
+// challenge50 forges a JavaScript snippet with the same CBC-MAC hash as the
+// target snippet and writes it to a file.
+func challenge50() error {
+	key := []byte("YELLOW SUBMARINE")
+	iv := make([]byte, aes.BlockSize)
+	target := []byte("alert('MZA who was that?');\n")
+
+	targetHash, err := aescbc.CBCMAC(target, key, iv)
+	if err != nil {
+		return err
+	}
+	if hex.EncodeToString(targetHash) != "296b8d7cb78a243dda4d0a61d33bbdd1" {
+		return fmt.Errorf("unexpected target hash %x", targetHash)
+	}
+
+	// The padding and glue block are commented out, so they must not contain
+	// a line break. Grow the chosen content until they don't.
+	chosen := []byte("alert('Ayo, the Wu is back!');")
+	var forged []byte
+	for {
+		forged, err = aescbc.CBCMACCollision(target, append(chosen, "//"...), key, iv)
+		if err != nil {
+			return err
+		}
+		garbage := forged[len(chosen) : len(forged)-len(target)+aes.BlockSize]
+		if !bytes.ContainsAny(garbage, "\r\n") {
+			break
+		}
+		chosen = append(chosen, ' ')
+	}
+
+	forgedHash, err := aescbc.CBCMAC(forged, key, iv)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(forgedHash, targetHash) {
+		return fmt.Errorf("forged hash %x does not match %x", forgedHash, targetHash)
+	}
+
+	path := filepath.Join(os.TempDir(), "challenge50.js")
+	if err := os.WriteFile(path, forged, 0644); err != nil {
+		return err
+	}
+	color.Green("[+] Wrote %s with hash %x\n", path, forgedHash)
+	return nil
+}
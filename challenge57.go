@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/jessesomerville/cryptopals_set5/color"
+	dh "github.com/jessesomerville/cryptopals_set5/diffie_hellman"
+)
+
+// challenge57Group returns the group from challenge 57, where p-1 has many
+// small factors besides q.
+func challenge57Group() *dh.DHGroup {
+	p, _ := new(big.Int).SetString("7199773997391911030609999317773941274322764333428698921736339643928346453700085358802973900485592910475480089726140708102474957429903531369589969318716771", 10)
+	g, _ := new(big.Int).SetString("4565356397095740655436854503483826832136106141639563487732438195343690437606117828318042418238184896212352329118608100083187535033402010599512641674644143", 10)
+	q, _ := new(big.Int).SetString("236234353446506858198510045061214171961", 10)
+	return &dh.DHGroup{P: p, G: g, Q: q}
+}
+
+func challenge57() error {
+	group := challenge57Group()
+
+	victim, err := dh.NewStaticKeyVictim(nil, group)
+	if err != nil {
+		return err
+	}
+
+	result, err := dh.SmallSubgroupAttack(nil, group, victim.Respond, 1<<16)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("[+] Sent %d queries to the victim\n", result.Queries)
+
+	if result.Modulus.Cmp(group.Q) <= 0 {
+		return fmt.Errorf("only recovered the private key mod %d", result.Modulus)
+	}
+
+	pubKey := new(big.Int).Exp(group.G, result.Residue, group.P)
+	if pubKey.Cmp(victim.KeyPair.PubKey) != 0 {
+		return fmt.Errorf("recovered key %d does not match the victim's public key", result.Residue)
+	}
+	color.Green("[+] Recovered private key: %d\n", result.Residue)
+	return nil
+}
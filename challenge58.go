@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/jessesomerville/cryptopals_set5/color"
+	dh "github.com/jessesomerville/cryptopals_set5/diffie_hellman"
+)
+
+// challenge58Group returns the group from challenge 58, where (p-1)/q has
+// only a few small factors.
+func challenge58Group() *dh.DHGroup {
+	p, _ := new(big.Int).SetString("11470374874925275658116663507232161402086650258453896274534991676898999262641581519101074740642369848233294239851519212341844337347119899874391456329785623", 10)
+	g, _ := new(big.Int).SetString("622952335333961296978159266084741085889881358738459939978290179936063635566740258555167783009058567397963466103140082647486611657350811560630587013183357", 10)
+	q, _ := new(big.Int).SetString("335062023296420808191071248367701059461", 10)
+	return &dh.DHGroup{P: p, G: g, Q: q}
+}
+
+func challenge58() error {
+	group := challenge58Group()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	// Warm up by taking logs of y = g^x for x in [0, 2^20] and [0, 2^40].
+	for _, bits := range []uint{20, 40} {
+		b := new(big.Int).Lsh(big.NewInt(1), bits)
+		x, err := rand.Int(rand.Reader, b)
+		if err != nil {
+			return err
+		}
+		y := new(big.Int).Exp(group.G, x, group.P)
+
+		result, err := dh.Kangaroo(ctx, group, y, new(big.Int), b, dh.KangarooParams{})
+		if err != nil {
+			return err
+		}
+		if result.X.Cmp(x) != 0 {
+			return fmt.Errorf("kangaroo found %d, want %d", result.X, x)
+		}
+		fmt.Printf("[+] Found %d-bit log %d in %d multiplications\n", bits, result.X, result.Multiplications())
+	}
+
+	victim, err := dh.NewStaticKeyVictim(nil, group)
+	if err != nil {
+		return err
+	}
+
+	partial, err := dh.SmallSubgroupAttack(nil, group, victim.Respond, 1<<16)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("[+] Recovered x = %d mod %d\n", partial.Residue, partial.Modulus)
+
+	x, result, err := dh.KangarooAttack(ctx, group, victim.KeyPair.PubKey, partial, dh.KangarooParams{})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("[+] Kangaroo took %d multiplications\n", result.Multiplications())
+
+	if new(big.Int).Exp(group.G, x, group.P).Cmp(victim.KeyPair.PubKey) != 0 {
+		return fmt.Errorf("recovered key %d does not match the victim's public key", x)
+	}
+	color.Green("[+] Recovered private key: %d\n", x)
+	return nil
+}
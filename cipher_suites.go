@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jessesomerville/cryptopals_set5/color"
+	socketclient "github.com/jessesomerville/cryptopals_set5/socket_client"
+)
+
+// cipherSuites negotiates a cipher suite between two clients, with ClientA
+// only accepting the given suites, and exchanges a message under it.
+func cipherSuites(accept ...string) error {
+	clientA, err := socketclient.NewDHSocketClient("ClientA", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	clientB, err := socketclient.NewDHSocketClient("ClientB", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	clientA.Suites = accept
+	clientB.Suites = socketclient.SuiteNames()
+	clientA.RequireFinished = true
+	clientB.RequireFinished = true
+
+	go clientA.Listen() // Start Peer listener
+
+	fmt.Println("[+] Waiting 1 second to attempt to connect")
+	time.Sleep(time.Second)
+
+	if err := clientB.Connect(clientA.Port); err != nil {
+		return err
+	}
+	defer clientB.Conn.Close()
+
+	if err := clientB.DoHandshake(clientA.Port); err != nil {
+		return err
+	}
+	fmt.Printf("[+] Finished handshake with %s\n", clientB.Suite.Name)
+
+	msg := socketclient.Message{
+		Type: 4,
+		Data: []byte("Hello"),
+	}
+	if err := clientB.SendMessage(clientB.Conn, msg); err != nil {
+		return err
+	}
+	respMsg, err := clientB.ReadMessage(clientB.Conn)
+	if err != nil {
+		return err
+	}
+	color.Blue("[+] %s received: %s\n\n", clientB.ID, string(respMsg.Data))
+	return nil
+}
+
+// suiteDowngrade has a MITM strip every suite but MODP-SHA1-CBC from the
+// offer. Without Finished messages neither side notices. With them, the
+// responder's transcript includes the stripped offer and the handshake fails.
+func suiteDowngrade(requireFinished bool) error {
+	clientA, err := socketclient.NewDHSocketClient("ClientA", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	clientB, err := socketclient.NewDHSocketClient("ClientB", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	mitm, err := socketclient.NewMITMSocketClient("MITM", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	clientB.Suites = socketclient.SuiteNames()
+	clientA.RequireFinished = requireFinished
+	clientB.RequireFinished = requireFinished
+	mitm.DowngradeTo = "MODP-SHA1-CBC"
+
+	go mitm.Listen()    // Start MITM listener
+	go clientA.Listen() // Start Peer listener
+
+	fmt.Println("[+] Waiting 1 second to attempt to connect")
+	time.Sleep(time.Second)
+
+	// Have ClientB connect to the MITM
+	if err := clientB.Connect(mitm.Port); err != nil {
+		return err
+	}
+	defer clientB.Conn.Close()
+
+	// Have the MITM connect to ClientA
+	if err := mitm.Connect(clientA.Port); err != nil {
+		return err
+	}
+	defer mitm.Conn.Close()
+
+	err = clientB.DoHandshake(mitm.Port)
+	if requireFinished {
+		if err == nil {
+			return fmt.Errorf("downgrade to %s was not detected", clientB.Suite.Name)
+		}
+		color.Green("[+] Downgrade detected: %v\n", err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	color.Red("[!] %s negotiated %s without noticing the downgrade\n", clientB.ID, clientB.Suite.Name)
+	return nil
+}
@@ -0,0 +1,151 @@
+package dh
+
+import (
+	"crypto/ecdh"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/jessesomerville/cryptopals_set5/randutil"
+)
+
+// Names of the supported key agreement schemes.
+const (
+	MODP   = "modp"
+	X25519 = "x25519"
+	P256   = "p256"
+)
+
+// KeyAgreement is a key pair for one of the supported key agreement schemes,
+// either finite-field DH or ECDH.
+type KeyAgreement interface {
+	// Name identifies the scheme.
+	Name() string
+	// PublicKey returns the encoded public key to send to the peer.
+	PublicKey() []byte
+	// SharedSecret computes the raw shared secret from the peer's encoded
+	// public key.
+	SharedSecret(peerPubKey []byte) ([]byte, error)
+}
+
+// GenerateKeyAgreement creates a key pair for the named scheme, reading the
+// private key from random, or from crypto/rand if random is nil. MODP key
+// pairs use the group from GetGroup.
+func GenerateKeyAgreement(random io.Reader, name string) (KeyAgreement, error) {
+	if name == MODP {
+		return GenerateKeyPair(random, GetGroup())
+	}
+	return GenerateECDHKeyPair(random, name)
+}
+
+// SessionKey derives a session key from the shared secret between the key
+// pair and the peer's public key. Every scheme hashes its secret the same way
+// ComputeSessionKey does.
+func SessionKey(agreement KeyAgreement, peerPubKey []byte) ([]byte, error) {
+	secret, err := agreement.SharedSecret(peerPubKey)
+	if err != nil {
+		return nil, err
+	}
+	return hashSecret(secret), nil
+}
+
+// Name implements KeyAgreement.
+func (key *DHKeyPair) Name() string {
+	return MODP
+}
+
+// PublicKey implements KeyAgreement.
+func (key *DHKeyPair) PublicKey() []byte {
+	return key.PubKey.Bytes()
+}
+
+// SharedSecret implements KeyAgreement. The secret is left-padded to the size
+// of p. Peer values outside [2, p-2] are rejected, since 0, 1 and p-1 force the
+// secret into a subgroup of order at most two.
+func (key *DHKeyPair) SharedSecret(peerPubKey []byte) ([]byte, error) {
+	peer := new(big.Int).SetBytes(peerPubKey)
+	pMinusTwo := new(big.Int).Sub(key.Group.P, two)
+	if peer.Cmp(two) < 0 || peer.Cmp(pMinusTwo) > 0 {
+		return nil, fmt.Errorf("DH public key %d is outside [2, p-2]", peer)
+	}
+	secret := new(big.Int).Exp(peer, key.privKey, key.Group.P)
+	return padSecret(key.Group, secret), nil
+}
+
+// ECDHKeyPair holds a key pair on one of the curves supported by crypto/ecdh.
+type ECDHKeyPair struct {
+	name    string
+	privKey *ecdh.PrivateKey
+}
+
+// maxECDHKeyAttempts bounds how many invalid private keys GenerateECDHKeyPair
+// reads before giving up.
+const maxECDHKeyAttempts = 100
+
+// GenerateECDHKeyPair creates a random key pair on the named curve, reading
+// the private key from random, or from crypto/rand if random is nil.
+//
+// The private key bytes are read directly instead of using
+// ecdh.Curve.GenerateKey, which ignores custom readers.
+func GenerateECDHKeyPair(random io.Reader, name string) (*ECDHKeyPair, error) {
+	curve, err := curveByName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every 32-byte string is a valid X25519 key, while P-256 rejects
+	// scalars that are zero or not below the group order, so retry those.
+	// An honest reader produces one with probability about 2^-32, so a reader
+	// that keeps doing so is broken.
+	keyBytes := make([]byte, 32)
+	for i := 0; i < maxECDHKeyAttempts; i++ {
+		if err := randutil.Read(random, keyBytes); err != nil {
+			return nil, fmt.Errorf("failed to generate %s key: %v", name, err)
+		}
+		privKey, err := curve.NewPrivateKey(keyBytes)
+		if err == nil {
+			return &ECDHKeyPair{name: name, privKey: privKey}, nil
+		}
+	}
+	return nil, fmt.Errorf("failed to generate %s key: no valid scalar after %d attempts", name, maxECDHKeyAttempts)
+}
+
+// Name implements KeyAgreement.
+func (key *ECDHKeyPair) Name() string {
+	return key.name
+}
+
+// PublicKey implements KeyAgreement.
+func (key *ECDHKeyPair) PublicKey() []byte {
+	return key.privKey.PublicKey().Bytes()
+}
+
+// SharedSecret implements KeyAgreement.
+func (key *ECDHKeyPair) SharedSecret(peerPubKey []byte) ([]byte, error) {
+	peer, err := key.privKey.Curve().NewPublicKey(peerPubKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s public key: %v", key.name, err)
+	}
+	secret, err := key.privKey.ECDH(peer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute %s shared secret: %v", key.name, err)
+	}
+	return secret, nil
+}
+
+func curveByName(name string) (ecdh.Curve, error) {
+	switch name {
+	case X25519:
+		return ecdh.X25519(), nil
+	case P256:
+		return ecdh.P256(), nil
+	}
+	return nil, fmt.Errorf("unsupported curve %q", name)
+}
+
+func hashSecret(secret []byte) []byte {
+	hash := sha1.New()
+	hash.Write(secret)
+	return hash.Sum(nil)
+}
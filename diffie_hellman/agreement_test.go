@@ -0,0 +1,36 @@
+package dh
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestDHSharedSecretRange(t *testing.T) {
+	key, err := GenerateKeyPair(nil, GetGroup())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := GetGroup().P
+
+	tests := []struct {
+		name string
+		peer *big.Int
+		ok   bool
+	}{
+		{"0", big.NewInt(0), false},
+		{"1", big.NewInt(1), false},
+		{"2", big.NewInt(2), true},
+		{"p-2", new(big.Int).Sub(p, two), true},
+		{"p-1", new(big.Int).Sub(p, one), false},
+		{"p", new(big.Int).Set(p), false},
+		{"p+2", new(big.Int).Add(p, two), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := key.SharedSecret(tt.peer.Bytes())
+			if ok := err == nil; ok != tt.ok {
+				t.Errorf("SharedSecret(%s) error = %v, want ok = %t", tt.name, err, tt.ok)
+			}
+		})
+	}
+}
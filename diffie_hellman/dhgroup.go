@@ -1,13 +1,23 @@
 package dh
 
 import (
+	"fmt"
+	"io"
 	"math/big"
 	"sync"
+
+	"github.com/jessesomerville/cryptopals_set5/randutil"
 )
 
+// MinGroupBits is the smallest prime size ValidateGroup will accept.
+const MinGroupBits = 1024
+
 var (
 	Group *DHGroup
 	once  sync.Once
+
+	one = big.NewInt(1)
+	two = big.NewInt(2)
 )
 
 // DHGroup holds the Diffie-Hellman prime and generator
@@ -16,6 +26,8 @@ type DHGroup struct {
 	P *big.Int
 	// Diffie-Hellman generator
 	G *big.Int
+	// Order of the subgroup generated by G. May be nil if unknown.
+	Q *big.Int
 }
 
 // GetGroup initializes the Diffie-Hellman group with the 1536-bit MODP Group
@@ -30,3 +42,115 @@ func GetGroup() *DHGroup {
 	})
 	return Group
 }
+
+// GenerateGroup creates a new group with a safe prime p = 2q + 1 of the given
+// size and a generator of the subgroup of prime order q, reading randomness
+// from random, or from crypto/rand if random is nil.
+func GenerateGroup(random io.Reader, bits int) (*DHGroup, error) {
+	if bits < 3 {
+		return nil, fmt.Errorf("group size of %d bits is too small", bits)
+	}
+
+	p, q := new(big.Int), new(big.Int)
+	for {
+		var err error
+		q, err = randutil.Prime(random, bits-1)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate subgroup order: %v", err)
+		}
+
+		// p = 2q + 1
+		p.Lsh(q, 1).Add(p, one)
+		if p.BitLen() == bits && p.ProbablyPrime(20) {
+			break
+		}
+	}
+
+	// Any square other than 1 generates the subgroup of order q.
+	pMinusThree := new(big.Int).Sub(p, big.NewInt(3))
+	g := new(big.Int)
+	for g.Cmp(one) <= 0 {
+		h, err := randutil.Int(random, pMinusThree)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate generator: %v", err)
+		}
+		h.Add(h, two) // h in [2, p-2]
+		g.Exp(h, two, p)
+	}
+
+	return &DHGroup{P: p, G: g, Q: q}, nil
+}
+
+// GroupReport holds the results of the individual checks run by ValidateGroup.
+type GroupReport struct {
+	// Size of p in bits
+	Bits int
+	// Order of the subgroup that was checked. If the group did not specify Q,
+	// this is (p-1)/2.
+	Q *big.Int
+
+	PrimeP      bool
+	PrimeQ      bool
+	GeneratorOK bool
+	SizeOK      bool
+}
+
+// OK reports whether every check in the report passed.
+func (r *GroupReport) OK() bool {
+	return r.PrimeP && r.PrimeQ && r.GeneratorOK && r.SizeOK
+}
+
+// Problems returns a description of each check that failed.
+func (r *GroupReport) Problems() []string {
+	var problems []string
+	if !r.PrimeP {
+		problems = append(problems, "p is not prime")
+	}
+	if !r.PrimeQ {
+		problems = append(problems, "q is not prime")
+	}
+	if !r.GeneratorOK {
+		problems = append(problems, "g does not generate the subgroup of order q")
+	}
+	if !r.SizeOK {
+		problems = append(problems, fmt.Sprintf("p is %d bits, need at least %d", r.Bits, MinGroupBits))
+	}
+	return problems
+}
+
+func (r *GroupReport) String() string {
+	if r.OK() {
+		return fmt.Sprintf("valid %d-bit group", r.Bits)
+	}
+	return fmt.Sprintf("invalid %d-bit group: %v", r.Bits, r.Problems())
+}
+
+// ValidateGroup checks that p and q are prime, that q divides p-1, that g has
+// order q and that p is at least MinGroupBits long.
+func ValidateGroup(grp *DHGroup) *GroupReport {
+	report := &GroupReport{}
+	if grp == nil || grp.P == nil || grp.G == nil || grp.P.Sign() <= 0 {
+		return report
+	}
+
+	p := grp.P
+	pMinusOne := new(big.Int).Sub(p, one)
+	report.Bits = p.BitLen()
+	report.SizeOK = report.Bits >= MinGroupBits
+	report.PrimeP = p.ProbablyPrime(20)
+
+	q := grp.Q
+	if q == nil {
+		q = new(big.Int).Rsh(pMinusOne, 1)
+	}
+	report.Q = q
+	report.PrimeQ = q.Sign() > 0 && q.ProbablyPrime(20) &&
+		new(big.Int).Mod(pMinusOne, q).Sign() == 0
+
+	// g must be in [2, p-2] and satisfy g^q = 1 mod p
+	g := grp.G
+	if g.Cmp(one) > 0 && g.Cmp(pMinusOne) < 0 {
+		report.GeneratorOK = new(big.Int).Exp(g, q, p).Cmp(one) == 0
+	}
+	return report
+}
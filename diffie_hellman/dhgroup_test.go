@@ -0,0 +1,110 @@
+package dh
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/jessesomerville/cryptopals_set5/randutil"
+)
+
+func TestValidateGroup(t *testing.T) {
+	tests := []struct {
+		name string
+		grp  *DHGroup
+		want GroupReport
+	}{
+		{
+			name: "RFC 3526 group",
+			grp:  GetGroup(),
+			want: GroupReport{Bits: 1536, PrimeP: true, PrimeQ: true, GeneratorOK: true, SizeOK: true},
+		},
+		{
+			// p = 2*509 + 1
+			name: "small safe prime",
+			grp:  &DHGroup{P: big.NewInt(1019), G: big.NewInt(4), Q: big.NewInt(509)},
+			want: GroupReport{Bits: 10, PrimeP: true, PrimeQ: true, GeneratorOK: true},
+		},
+		{
+			// 1023 = 3 * 11 * 31
+			name: "composite p",
+			grp:  &DHGroup{P: big.NewInt(1023), G: big.NewInt(4), Q: big.NewInt(511)},
+			want: GroupReport{Bits: 10},
+		},
+		{
+			// p-1 = 2^2 * 3^4 * 5^2, so (p-1)/2 is not prime.
+			name: "no large q",
+			grp:  &DHGroup{P: big.NewInt(8101), G: big.NewInt(36)},
+			want: GroupReport{Bits: 13, PrimeP: true, GeneratorOK: true},
+		},
+		{
+			name: "q does not divide p-1",
+			grp:  &DHGroup{P: big.NewInt(1019), G: big.NewInt(4), Q: big.NewInt(503)},
+			want: GroupReport{Bits: 10, PrimeP: true},
+		},
+		{
+			// 2 is a non-residue mod 1019, so it has order 1018.
+			name: "g outside subgroup",
+			grp:  &DHGroup{P: big.NewInt(1019), G: big.NewInt(2), Q: big.NewInt(509)},
+			want: GroupReport{Bits: 10, PrimeP: true, PrimeQ: true},
+		},
+		{
+			name: "g = p-1",
+			grp:  &DHGroup{P: big.NewInt(1019), G: big.NewInt(1018), Q: big.NewInt(509)},
+			want: GroupReport{Bits: 10, PrimeP: true, PrimeQ: true},
+		},
+		{
+			name: "g = 1",
+			grp:  &DHGroup{P: big.NewInt(1019), G: big.NewInt(1), Q: big.NewInt(509)},
+			want: GroupReport{Bits: 10, PrimeP: true, PrimeQ: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ValidateGroup(tt.grp)
+			if got.Bits != tt.want.Bits || got.PrimeP != tt.want.PrimeP || got.PrimeQ != tt.want.PrimeQ ||
+				got.GeneratorOK != tt.want.GeneratorOK || got.SizeOK != tt.want.SizeOK {
+				t.Errorf("ValidateGroup = %+v, want %+v", got, tt.want)
+			}
+			if got.OK() != tt.want.OK() || len(got.Problems()) != len(tt.want.Problems()) {
+				t.Errorf("ValidateGroup: %s, want %s", got, &tt.want)
+			}
+		})
+	}
+
+	if ValidateGroup(nil).OK() {
+		t.Error("ValidateGroup(nil) is OK")
+	}
+}
+
+func TestGenerateGroup(t *testing.T) {
+	for _, bits := range []int{16, 128, 256} {
+		grp, err := GenerateGroup(randutil.NewSeeded([]byte("group")), bits)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// p = 2q + 1
+		if p := new(big.Int).Lsh(grp.Q, 1); p.Add(p, one).Cmp(grp.P) != 0 {
+			t.Errorf("GenerateGroup(%d): p = %d is not 2q + 1 for q = %d", bits, grp.P, grp.Q)
+		}
+		report := ValidateGroup(grp)
+		if report.Bits != bits || !report.PrimeP || !report.PrimeQ || !report.GeneratorOK {
+			t.Errorf("GenerateGroup(%d): %s", bits, report)
+		}
+		if report.SizeOK {
+			t.Errorf("GenerateGroup(%d): SizeOK = true below %d bits", bits, MinGroupBits)
+		}
+
+		again, err := GenerateGroup(randutil.NewSeeded([]byte("group")), bits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if again.P.Cmp(grp.P) != 0 || again.G.Cmp(grp.G) != 0 {
+			t.Errorf("GenerateGroup(%d) with the same seed gave different groups", bits)
+		}
+	}
+
+	if _, err := GenerateGroup(nil, 2); err == nil {
+		t.Error("GenerateGroup(2) succeeded, want error")
+	}
+}
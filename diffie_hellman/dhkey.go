@@ -1,9 +1,11 @@
 package dh
 
 import (
-	"crypto/rand"
 	"fmt"
+	"io"
 	"math/big"
+
+	"github.com/jessesomerville/cryptopals_set5/randutil"
 )
 
 // DHKeyPair holds a public and private key pair and the associated Diffie-Hellman group
@@ -15,16 +17,23 @@ type DHKeyPair struct {
 }
 
 // GenerateKeyPair creates a random private key in (0, p) and generates the
-// associated public key.
-func GenerateKeyPair(group *DHGroup) (*DHKeyPair, error) {
-	privKey, err := rand.Int(rand.Reader, group.P)
+// associated public key. If the group specifies the order q of its generator,
+// the private key is taken from (0, q) instead. The private key is read from
+// random, or from crypto/rand if random is nil.
+func GenerateKeyPair(random io.Reader, group *DHGroup) (*DHKeyPair, error) {
+	max := group.P
+	if group.Q != nil {
+		max = group.Q
+	}
+
+	privKey, err := randutil.Int(random, max)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate private key: %v", err)
 	}
 
 	zero := big.NewInt(0)
 	for privKey.Cmp(zero) == 0 {
-		privKey, err = rand.Int(rand.Reader, group.P)
+		privKey, err = randutil.Int(random, max)
 		if err != nil {
 			return nil, fmt.Errorf("failed to regenerate random int: %v", err)
 		}
@@ -38,3 +47,13 @@ func GenerateKeyPair(group *DHGroup) (*DHKeyPair, error) {
 	key.Group = group
 	return key, nil
 }
+
+// NewKeyPair builds the key pair for a known private key, such as one
+// recovered by an attack.
+func NewKeyPair(group *DHGroup, privKey *big.Int) *DHKeyPair {
+	return &DHKeyPair{
+		privKey: new(big.Int).Set(privKey),
+		PubKey:  new(big.Int).Exp(group.G, privKey, group.P),
+		Group:   group,
+	}
+}
@@ -0,0 +1,150 @@
+package dh
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+)
+
+// KangarooParams tunes Pollard's kangaroo algorithm. The pseudo-random jump
+// function is f(y) = 2^(y mod K), and the tame kangaroo makes N jumps before
+// setting its trap.
+type KangarooParams struct {
+	// Number of distinct jump sizes. Zero picks K from the interval size.
+	K int
+	// Number of jumps made by the tame kangaroo. Zero uses 4 times the mean
+	// jump size.
+	N int64
+}
+
+// KangarooResult holds the discrete log found by Kangaroo along with the work
+// done to find it.
+type KangarooResult struct {
+	X *big.Int
+
+	TameJumps int64
+	WildJumps int64
+}
+
+// Multiplications returns the number of modular multiplications performed.
+func (r *KangarooResult) Multiplications() int64 {
+	return r.TameJumps + r.WildJumps
+}
+
+// ctxCheckInterval is how many jumps are made between checks for cancellation.
+const ctxCheckInterval = 1 << 12
+
+// Kangaroo finds x in [a, b] such that y = g^x mod p using Pollard's kangaroo
+// (lambda) algorithm. It runs in roughly O(sqrt(b-a)) time and returns an
+// error if the wild kangaroo escapes without landing in the trap, which means
+// the log is not in the interval (or the parameters were unlucky).
+func Kangaroo(ctx context.Context, grp *DHGroup, y, a, b *big.Int, params KangarooParams) (*KangarooResult, error) {
+	width := new(big.Int).Sub(b, a)
+	if width.Sign() < 0 {
+		return nil, fmt.Errorf("invalid interval [%d, %d]", a, b)
+	}
+
+	k := params.K
+	if k == 0 {
+		// Aim for a mean jump size around sqrt(b-a)/2.
+		k = width.BitLen()/2 + 1
+	}
+	if k < 1 || k > 62 {
+		return nil, fmt.Errorf("jump parameter k = %d out of range", k)
+	}
+
+	// Precompute the jump sizes 2^i and the multipliers g^(2^i).
+	jumps := make([]*big.Int, k)
+	steps := make([]*big.Int, k)
+	var total int64
+	for i := range jumps {
+		jumps[i] = big.NewInt(1 << i)
+		steps[i] = new(big.Int).Exp(grp.G, jumps[i], grp.P)
+		total += 1 << i
+	}
+
+	n := params.N
+	if n == 0 {
+		n = 4 * (total / int64(k))
+	}
+
+	result := &KangarooResult{}
+	kBig := big.NewInt(int64(k))
+	mod := new(big.Int)
+	jumpIndex := func(y *big.Int) int {
+		return int(mod.Mod(y, kBig).Int64())
+	}
+
+	// The tame kangaroo starts at g^b and leaves a trap where it stops.
+	xT := new(big.Int)
+	yT := new(big.Int).Exp(grp.G, b, grp.P)
+	for i := int64(0); i < n; i++ {
+		if i%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return result, err
+			}
+		}
+		j := jumpIndex(yT)
+		xT.Add(xT, jumps[j])
+		yT.Mul(yT, steps[j]).Mod(yT, grp.P)
+		result.TameJumps++
+	}
+
+	// The wild kangaroo starts at y and runs until it falls in the trap or
+	// passes it.
+	limit := new(big.Int).Add(width, xT)
+	xW := new(big.Int)
+	yW := new(big.Int).Set(y)
+	for xW.Cmp(limit) <= 0 {
+		if result.WildJumps%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return result, err
+			}
+		}
+		if yW.Cmp(yT) == 0 {
+			// b + xT - xW
+			result.X = new(big.Int).Add(b, xT)
+			result.X.Sub(result.X, xW)
+			return result, nil
+		}
+		j := jumpIndex(yW)
+		xW.Add(xW, jumps[j])
+		yW.Mul(yW, steps[j]).Mod(yW, grp.P)
+		result.WildJumps++
+	}
+	return result, fmt.Errorf("wild kangaroo escaped after %d jumps", result.WildJumps)
+}
+
+// KangarooAttack finishes a small-subgroup attack against a victim with the
+// public key y. Knowing x = n mod r, it writes x = n + m*r and uses Kangaroo
+// to find m in [0, (q-1)/r] as the log of y * g^-n to the base g^r.
+func KangarooAttack(ctx context.Context, grp *DHGroup, y *big.Int, partial *SubgroupResult, params KangarooParams) (*big.Int, *KangarooResult, error) {
+	if grp.Q == nil {
+		return nil, nil, fmt.Errorf("group does not specify the order of its generator")
+	}
+	n, r := partial.Residue, partial.Modulus
+
+	// y' = y * g^-n
+	gInv := new(big.Int).ModInverse(grp.G, grp.P)
+	yPrime := new(big.Int).Exp(gInv, n, grp.P)
+	yPrime.Mul(yPrime, y).Mod(yPrime, grp.P)
+
+	// g' = g^r
+	subGroup := &DHGroup{
+		P: grp.P,
+		G: new(big.Int).Exp(grp.G, r, grp.P),
+	}
+
+	upper := new(big.Int).Sub(grp.Q, one)
+	upper.Div(upper, r)
+
+	result, err := Kangaroo(ctx, subGroup, yPrime, new(big.Int), upper, params)
+	if err != nil {
+		return nil, result, err
+	}
+
+	// x = n + m*r
+	x := new(big.Int).Mul(result.X, r)
+	x.Add(x, n)
+	return x, result, nil
+}
@@ -0,0 +1,77 @@
+package dh
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// kangarooGroup has p = 2*8388953 + 1, and g = 4 has prime order 8388953.
+var kangarooGroup = &DHGroup{P: big.NewInt(16777907), G: big.NewInt(4), Q: big.NewInt(8388953)}
+
+func TestKangaroo(t *testing.T) {
+	a, b := big.NewInt(1000000), big.NewInt(1100000)
+	for _, x := range []int64{1000000, 1000001, 1031337, 1099999, 1100000} {
+		y := new(big.Int).Exp(kangarooGroup.G, big.NewInt(x), kangarooGroup.P)
+		result, err := Kangaroo(context.Background(), kangarooGroup, y, a, b, KangarooParams{})
+		if err != nil {
+			t.Errorf("Kangaroo(%d): %v", x, err)
+			continue
+		}
+		if result.X.Int64() != x {
+			t.Errorf("Kangaroo(%d) = %d", x, result.X)
+		}
+	}
+}
+
+func TestKangarooNotFound(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// The log is far outside the interval, so the wild kangaroo must pass the
+	// trap and give up.
+	a, b := big.NewInt(0), big.NewInt(100000)
+	y := new(big.Int).Exp(kangarooGroup.G, big.NewInt(5000000), kangarooGroup.P)
+	result, err := Kangaroo(ctx, kangarooGroup, y, a, b, KangarooParams{})
+	if err == nil {
+		t.Fatalf("Kangaroo found %d outside [%d, %d]", result.X, a, b)
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Kangaroo did not give up before the deadline")
+	}
+	if result.WildJumps == 0 {
+		t.Errorf("wild kangaroo made no jumps")
+	}
+
+	if _, err := Kangaroo(ctx, kangarooGroup, y, b, a, KangarooParams{}); err == nil {
+		t.Error("Kangaroo accepted an empty interval")
+	}
+}
+
+func TestKangarooCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	y := new(big.Int).Exp(kangarooGroup.G, big.NewInt(1234), kangarooGroup.P)
+	_, err := Kangaroo(ctx, kangarooGroup, y, big.NewInt(0), big.NewInt(100000), KangarooParams{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Kangaroo error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestKangarooAttack(t *testing.T) {
+	x := big.NewInt(7654321)
+	y := new(big.Int).Exp(kangarooGroup.G, x, kangarooGroup.P)
+	r := big.NewInt(1000)
+	partial := &SubgroupResult{Residue: new(big.Int).Mod(x, r), Modulus: r}
+
+	got, _, err := KangarooAttack(context.Background(), kangarooGroup, y, partial, KangarooParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Cmp(x) != 0 {
+		t.Errorf("KangarooAttack = %d, want %d", got, x)
+	}
+}
@@ -2,11 +2,9 @@ package dh
 
 import (
 	"bytes"
-	"crypto/sha1"
 	"encoding/base64"
 	"encoding/gob"
 	"fmt"
-	"io"
 	"math/big"
 )
 
@@ -14,14 +12,20 @@ import (
 // private key, the peer publicKey, and the Diffie-Hellman prime.
 func ComputeSessionKey(clientKeyPair *DHKeyPair, peerPubKey *big.Int) []byte {
 	sessionKey := new(big.Int).Exp(peerPubKey, clientKeyPair.privKey, clientKeyPair.Group.P)
+	return DeriveKey(clientKeyPair.Group, sessionKey)
+}
 
-	blen := (clientKeyPair.Group.P.BitLen() + 7) / 8
-	paddedSessionKey := make([]byte, blen)
-	copyWithLeftPad(paddedSessionKey, sessionKey.Bytes())
+// DeriveKey hashes a raw shared secret in the given group into a session key.
+func DeriveKey(grp *DHGroup, secret *big.Int) []byte {
+	return hashSecret(padSecret(grp, secret))
+}
 
-	hash := sha1.New()
-	io.WriteString(hash, string(paddedSessionKey))
-	return hash.Sum(nil)
+// padSecret encodes the secret left-padded to the size of p.
+func padSecret(grp *DHGroup, secret *big.Int) []byte {
+	blen := (grp.P.BitLen() + 7) / 8
+	paddedSecret := make([]byte, blen)
+	copyWithLeftPad(paddedSecret, secret.Bytes())
+	return paddedSecret
 }
 
 // SerializeDHGroup serializes a Diffie-Hellman group
@@ -0,0 +1,179 @@
+package dh
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/jessesomerville/cryptopals_set5/randutil"
+)
+
+// StaticKeyVictim holds a long-lived key pair and answers every public key it
+// is sent with a message MAC'd under the resulting shared secret. It does not
+// check that the public key lies in the subgroup generated by G.
+type StaticKeyVictim struct {
+	KeyPair *DHKeyPair
+	Message []byte
+}
+
+// NewStaticKeyVictim generates a static key pair in the group, reading the
+// private key from random, or from crypto/rand if random is nil.
+func NewStaticKeyVictim(random io.Reader, grp *DHGroup) (*StaticKeyVictim, error) {
+	keyPair, err := GenerateKeyPair(random, grp)
+	if err != nil {
+		return nil, err
+	}
+	return &StaticKeyVictim{
+		KeyPair: keyPair,
+		Message: []byte("crazy flamboyant for the rap enjoyment"),
+	}, nil
+}
+
+// Respond computes K = h^x mod p and returns the victim's message along with
+// HMAC-SHA256(DeriveKey(K), message).
+func (v *StaticKeyVictim) Respond(h *big.Int) (msg, mac []byte) {
+	key := ComputeSessionKey(v.KeyPair, h)
+	return v.Message, computeMAC(key, v.Message)
+}
+
+// SubgroupResult holds what a small-subgroup attack learned about a private
+// key x, namely x mod Modulus.
+type SubgroupResult struct {
+	Residue *big.Int
+	Modulus *big.Int
+	// Number of queries sent to the victim
+	Queries int
+}
+
+// SmallSubgroupAttack recovers the victim's private key modulo the product of
+// the distinct primes below bound that divide (p-1)/q. For each such prime r
+// it sends the victim an element of order r, brute-forces the shared secret
+// from the returned MAC to learn x mod r, and combines the residues with the
+// Chinese remainder theorem. It stops early once the product of the moduli
+// exceeds q, at which point the residue is the full private key. The elements
+// sent are picked using random, or crypto/rand if random is nil.
+func SmallSubgroupAttack(random io.Reader, grp *DHGroup, respond func(h *big.Int) (msg, mac []byte), bound int64) (*SubgroupResult, error) {
+	if grp.Q == nil {
+		return nil, fmt.Errorf("group does not specify the order of its generator")
+	}
+
+	j := new(big.Int).Sub(grp.P, one)
+	j.Div(j, grp.Q)
+
+	result := &SubgroupResult{}
+	var residues, moduli []*big.Int
+	product := big.NewInt(1)
+	for _, r := range SmallFactors(j, bound) {
+		// Skip factors that also divide q, their residues would be wrong.
+		if new(big.Int).Mod(grp.Q, r).Sign() == 0 {
+			continue
+		}
+
+		h, err := SubgroupElement(random, grp, r)
+		if err != nil {
+			return nil, err
+		}
+
+		msg, mac := respond(h)
+		result.Queries++
+
+		b, err := bruteForceMAC(grp, h, r, msg, mac)
+		if err != nil {
+			return nil, err
+		}
+
+		residues = append(residues, b)
+		moduli = append(moduli, r)
+		product.Mul(product, r)
+		if product.Cmp(grp.Q) > 0 {
+			break
+		}
+	}
+
+	if len(moduli) == 0 {
+		return nil, fmt.Errorf("no factors of (p-1)/q below %d", bound)
+	}
+	result.Residue, result.Modulus = CRT(residues, moduli)
+	return result, nil
+}
+
+// SmallFactors returns the distinct primes below bound that divide n, in
+// increasing order.
+func SmallFactors(n *big.Int, bound int64) []*big.Int {
+	var factors []*big.Int
+	rem := new(big.Int).Set(n)
+	mod := new(big.Int)
+	for i := int64(2); i < bound && rem.Cmp(one) > 0; i++ {
+		r := big.NewInt(i)
+		if mod.Mod(rem, r).Sign() != 0 {
+			continue
+		}
+		factors = append(factors, r)
+		for mod.Mod(rem, r).Sign() == 0 {
+			rem.Div(rem, r)
+		}
+	}
+	return factors
+}
+
+// SubgroupElement returns a random element of order r in the group, where r
+// is a prime dividing p-1, reading randomness from random, or from
+// crypto/rand if random is nil.
+func SubgroupElement(random io.Reader, grp *DHGroup, r *big.Int) (*big.Int, error) {
+	exp := new(big.Int).Sub(grp.P, one)
+	exp.Div(exp, r)
+
+	h := big.NewInt(1)
+	for h.Cmp(one) == 0 {
+		rnd, err := randutil.Int(random, grp.P)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate random element: %v", err)
+		}
+		if rnd.Sign() == 0 {
+			continue
+		}
+		h.Exp(rnd, exp, grp.P)
+	}
+	return h, nil
+}
+
+// CRT combines x = residues[i] mod moduli[i] into x mod the product of the
+// moduli, which must be pairwise coprime.
+func CRT(residues, moduli []*big.Int) (x, modulus *big.Int) {
+	modulus = big.NewInt(1)
+	for _, m := range moduli {
+		modulus.Mul(modulus, m)
+	}
+
+	x = new(big.Int)
+	for i, m := range moduli {
+		ms := new(big.Int).Div(modulus, m)
+		inv := new(big.Int).ModInverse(ms, m)
+		term := new(big.Int).Mul(residues[i], ms)
+		term.Mul(term, inv)
+		x.Add(x, term)
+	}
+	x.Mod(x, modulus)
+	return x, modulus
+}
+
+// bruteForceMAC finds b in [0, r) such that the MAC computed with h^b as the
+// shared secret matches mac.
+func bruteForceMAC(grp *DHGroup, h, r *big.Int, msg, mac []byte) (*big.Int, error) {
+	k := big.NewInt(1)
+	for b := int64(0); b < r.Int64(); b++ {
+		if hmac.Equal(computeMAC(DeriveKey(grp, k), msg), mac) {
+			return big.NewInt(b), nil
+		}
+		k.Mul(k, h).Mod(k, grp.P)
+	}
+	return nil, fmt.Errorf("no residue mod %d matches the MAC", r)
+}
+
+func computeMAC(key, msg []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(msg)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,52 @@
+package dlog
+
+import (
+	"fmt"
+	"math/big"
+
+	dh "github.com/jessesomerville/cryptopals_set5/diffie_hellman"
+)
+
+// MaxBSGSBits is the largest group order BSGS will attempt, since it stores
+// sqrt(n) group elements.
+const MaxBSGSBits = 48
+
+// BSGS finds x in [0, n) such that g^x = h mod p using Shanks' baby-step
+// giant-step algorithm, where n is the order of g.
+func BSGS(grp *dh.DHGroup, h, n *big.Int) (*big.Int, error) {
+	if n.BitLen() > MaxBSGSBits {
+		return nil, fmt.Errorf("order of %d bits is too large for baby-step giant-step", n.BitLen())
+	}
+
+	// m = ceil(sqrt(n))
+	m := new(big.Int).Sqrt(n)
+	if new(big.Int).Mul(m, m).Cmp(n) < 0 {
+		m.Add(m, one)
+	}
+	steps := m.Int64()
+
+	// Baby steps: g^j for j in [0, m)
+	table := make(map[string]int64, steps)
+	e := big.NewInt(1)
+	for j := int64(0); j < steps; j++ {
+		key := string(e.Bytes())
+		if _, ok := table[key]; !ok {
+			table[key] = j
+		}
+		e.Mul(e, grp.G).Mod(e, grp.P)
+	}
+
+	// Giant steps: h * g^(-im) for i in [0, m)
+	factor := new(big.Int).ModInverse(grp.G, grp.P)
+	factor.Exp(factor, m, grp.P)
+	gamma := new(big.Int).Mod(h, grp.P)
+	for i := int64(0); i < steps; i++ {
+		if j, ok := table[string(gamma.Bytes())]; ok {
+			x := big.NewInt(i)
+			x.Mul(x, m).Add(x, big.NewInt(j))
+			return x.Mod(x, n), nil
+		}
+		gamma.Mul(gamma, factor).Mod(gamma, grp.P)
+	}
+	return nil, fmt.Errorf("no discrete log found for h = %d", h)
+}
@@ -0,0 +1,194 @@
+// Package dlog solves discrete logarithms g^x = h in Diffie-Hellman groups.
+// It is meant for measuring how weak a group is and for recovering private
+// keys from groups whose order has only small prime factors.
+package dlog
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+
+	dh "github.com/jessesomerville/cryptopals_set5/diffie_hellman"
+)
+
+var (
+	one = big.NewInt(1)
+	two = big.NewInt(2)
+)
+
+// Factor is a prime power dividing a group order.
+type Factor struct {
+	Prime *big.Int
+	Exp   int
+}
+
+// Order returns the order of the group's generator if the group specifies it
+// and p-1 otherwise.
+func Order(grp *dh.DHGroup) *big.Int {
+	if grp.Q != nil {
+		return new(big.Int).Set(grp.Q)
+	}
+	return new(big.Int).Sub(grp.P, one)
+}
+
+// FactorOrder factors n by trial division up to bound, then splits what is
+// left with Pollard's rho factoring method. It returns an error if a composite
+// cofactor cannot be split, which happens when n has no small prime factors
+// left.
+func FactorOrder(n *big.Int, bound int64) ([]Factor, error) {
+	if n.Sign() <= 0 {
+		return nil, fmt.Errorf("cannot factor non-positive order %d", n)
+	}
+
+	var factors []Factor
+	rem := new(big.Int).Set(n)
+	mod := new(big.Int)
+	for i := int64(2); i < bound && rem.Cmp(one) > 0; i++ {
+		r := big.NewInt(i)
+		exp := 0
+		for mod.Mod(rem, r).Sign() == 0 {
+			rem.Div(rem, r)
+			exp++
+		}
+		if exp > 0 {
+			factors = append(factors, Factor{Prime: r, Exp: exp})
+		}
+	}
+
+	primes := map[string]*Factor{}
+	var order []string
+	var split func(m *big.Int) error
+	split = func(m *big.Int) error {
+		if m.Cmp(one) == 0 {
+			return nil
+		}
+		if m.ProbablyPrime(20) {
+			key := m.String()
+			if f, ok := primes[key]; ok {
+				f.Exp++
+			} else {
+				primes[key] = &Factor{Prime: m, Exp: 1}
+				order = append(order, key)
+			}
+			return nil
+		}
+
+		d := rhoFactor(m)
+		if d == nil {
+			return fmt.Errorf("cofactor %d has no factors below %d and could not be split", m, bound)
+		}
+		if err := split(d); err != nil {
+			return err
+		}
+		return split(new(big.Int).Div(m, d))
+	}
+	if err := split(rem); err != nil {
+		return factors, err
+	}
+
+	// Large factors are added in increasing order.
+	sort.Slice(order, func(i, j int) bool {
+		return primes[order[i]].Prime.Cmp(primes[order[j]].Prime) < 0
+	})
+	for _, key := range order {
+		factors = append(factors, *primes[key])
+	}
+	return factors, nil
+}
+
+// rhoFactorSteps bounds the work rhoFactor spends on a single attempt, which
+// is enough to find factors of around 2*log2(rhoFactorSteps) bits.
+const rhoFactorSteps = 1 << 24
+
+// rhoFactor returns a nontrivial factor of the composite n using Pollard's
+// rho method with Brent's cycle detection, or nil if none is found.
+func rhoFactor(n *big.Int) *big.Int {
+	if n.Bit(0) == 0 {
+		return big.NewInt(2)
+	}
+
+	d := new(big.Int)
+	diff := new(big.Int)
+	for c := int64(1); c < 8; c++ {
+		cBig := big.NewInt(c)
+		f := func(x *big.Int) {
+			x.Mul(x, x).Add(x, cBig).Mod(x, n)
+		}
+
+		x := big.NewInt(2)
+		y := new(big.Int).Set(x)
+		ys := new(big.Int)
+		q := big.NewInt(1)
+		for power, lam := int64(1), int64(0); power < rhoFactorSteps; {
+			if lam >= power {
+				x.Set(y)
+				power <<= 1
+				lam = 0
+			}
+
+			// Accumulate a batch of differences and take a single gcd.
+			ys.Set(y)
+			for i := 0; i < rhoBatch; i++ {
+				f(y)
+				diff.Sub(x, y).Abs(diff)
+				q.Mul(q, diff).Mod(q, n)
+			}
+			lam += rhoBatch
+			if d.GCD(nil, nil, q, n).Cmp(one) == 0 {
+				continue
+			}
+
+			// Replay the batch one step at a time to find the factor.
+			for i := 0; i < rhoBatch; i++ {
+				f(ys)
+				diff.Sub(x, ys).Abs(diff)
+				if d.GCD(nil, nil, diff, n).Cmp(one) != 0 {
+					break
+				}
+			}
+			if d.Cmp(n) == 0 {
+				break
+			}
+			return new(big.Int).Set(d)
+		}
+	}
+	return nil
+}
+
+// rhoBatch is how many steps rhoFactor takes between gcd computations.
+const rhoBatch = 128
+
+// Analysis describes how hard discrete logs are in a group.
+type Analysis struct {
+	Order   *big.Int
+	Factors []Factor
+	// Size in bits of the largest prime factor of the order. Pohlig-Hellman
+	// reduces the problem to logs in a group of this size.
+	LargestPrimeBits int
+	// Whether Pohlig-Hellman is practical, meaning the largest prime factor is
+	// at most MaxRhoBits long.
+	Smooth bool
+}
+
+func (a *Analysis) String() string {
+	return fmt.Sprintf("order has %d prime factors, largest is %d bits (smooth: %t)", len(a.Factors), a.LargestPrimeBits, a.Smooth)
+}
+
+// Analyze factors the order of the group's generator using trial division up
+// to bound.
+func Analyze(grp *dh.DHGroup, bound int64) (*Analysis, error) {
+	order := Order(grp)
+	factors, err := FactorOrder(order, bound)
+	if err != nil {
+		return nil, err
+	}
+
+	analysis := &Analysis{Order: order, Factors: factors}
+	for _, f := range factors {
+		if bits := f.Prime.BitLen(); bits > analysis.LargestPrimeBits {
+			analysis.LargestPrimeBits = bits
+		}
+	}
+	analysis.Smooth = analysis.LargestPrimeBits <= MaxRhoBits
+	return analysis, nil
+}
@@ -0,0 +1,130 @@
+package dlog
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	dh "github.com/jessesomerville/cryptopals_set5/diffie_hellman"
+	"github.com/jessesomerville/cryptopals_set5/randutil"
+)
+
+// Small groups with known logs. In each, the no-solution h is outside the
+// subgroup generated by g.
+var (
+	// p = 2*509 + 1, and g = 4 has prime order 509. 2 is a non-residue.
+	bsgsGroup = &dh.DHGroup{P: big.NewInt(1019), G: big.NewInt(4), Q: big.NewInt(509)}
+	// p = 2*8388953 + 1, and g = 4 has prime order 8388953. p = 3 mod 4, so
+	// p-1 is a non-residue.
+	rhoGroup = &dh.DHGroup{P: big.NewInt(16777907), G: big.NewInt(4), Q: big.NewInt(8388953)}
+	// p-1 = 2^2 * 3^4 * 5^2, and g = 6 generates the whole group.
+	smoothGroup = &dh.DHGroup{P: big.NewInt(8101), G: big.NewInt(6)}
+	// g = 36 = 6^2 has order (p-1)/2, which leaves out 6 itself.
+	smoothSubgroup = &dh.DHGroup{P: big.NewInt(8101), G: big.NewInt(36), Q: big.NewInt(4050)}
+)
+
+func TestBSGS(t *testing.T) {
+	x, err := BSGS(bsgsGroup, big.NewInt(504), bsgsGroup.Q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x.Int64() != 123 {
+		t.Errorf("BSGS = %d, want 123", x)
+	}
+
+	if x, err := BSGS(bsgsGroup, big.NewInt(2), bsgsGroup.Q); err == nil {
+		t.Errorf("BSGS of a non-residue = %d, want error", x)
+	}
+}
+
+func TestPollardRho(t *testing.T) {
+	random := randutil.NewSeeded([]byte("pollard rho"))
+	x, err := PollardRho(random, rhoGroup, big.NewInt(9465905), rhoGroup.Q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x.Int64() != 5000000 {
+		t.Errorf("PollardRho = %d, want 5000000", x)
+	}
+
+	h := new(big.Int).Sub(rhoGroup.P, one)
+	if x, err := PollardRho(random, rhoGroup, h, rhoGroup.Q); err == nil {
+		t.Errorf("PollardRho of a non-residue = %d, want error", x)
+	}
+	if x, err := PollardRho(random, rhoGroup, big.NewInt(9465905), big.NewInt(8388954)); err == nil {
+		t.Errorf("PollardRho with a composite order = %d, want error", x)
+	}
+}
+
+func TestPohligHellman(t *testing.T) {
+	tests := []struct {
+		name string
+		grp  *dh.DHGroup
+		h    int64
+	}{
+		{"whole group", smoothGroup, 93},
+		{"subgroup", smoothSubgroup, 548},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x, err := PohligHellman(randutil.NewSeeded([]byte(tt.name)), tt.grp, big.NewInt(tt.h))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if x.Int64() != 1234 {
+				t.Errorf("PohligHellman = %d, want 1234", x)
+			}
+		})
+	}
+
+	if x, err := PohligHellman(nil, smoothSubgroup, big.NewInt(6)); err == nil {
+		t.Errorf("PohligHellman of h outside the subgroup = %d, want error", x)
+	}
+	if x, err := PohligHellman(nil, rhoGroup, big.NewInt(9465905)); err != nil || x.Int64() != 5000000 {
+		t.Errorf("PohligHellman on a prime order group = %v, %v, want 5000000", x, err)
+	}
+}
+
+func TestFactorOrder(t *testing.T) {
+	tests := []struct {
+		name  string
+		n     *big.Int
+		bound int64
+		want  []Factor
+	}{
+		{
+			name:  "trial division",
+			n:     big.NewInt(8100),
+			bound: 1 << 16,
+			want:  []Factor{{big.NewInt(2), 2}, {big.NewInt(3), 4}, {big.NewInt(5), 2}},
+		},
+		{
+			name:  "rho split",
+			n:     new(big.Int).Mul(big.NewInt(12*1000033), big.NewInt(1000003*1000003)),
+			bound: 100,
+			want:  []Factor{{big.NewInt(2), 2}, {big.NewInt(3), 1}, {big.NewInt(1000003), 2}, {big.NewInt(1000033), 1}},
+		},
+		{
+			name:  "one",
+			n:     big.NewInt(1),
+			bound: 100,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FactorOrder(tt.n, tt.bound)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FactorOrder(%d) = %v, want %v", tt.n, got, tt.want)
+			}
+		})
+	}
+
+	for _, n := range []int64{0, -8100} {
+		if got, err := FactorOrder(big.NewInt(n), 100); err == nil {
+			t.Errorf("FactorOrder(%d) = %v, want error", n, got)
+		}
+	}
+}
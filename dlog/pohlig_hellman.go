@@ -0,0 +1,156 @@
+package dlog
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+
+	dh "github.com/jessesomerville/cryptopals_set5/diffie_hellman"
+	"github.com/jessesomerville/cryptopals_set5/randutil"
+)
+
+// FactorBound is the trial division bound PohligHellman uses to factor the
+// group order.
+const FactorBound = 1 << 16
+
+// PohligHellman finds x such that g^x = h mod p when the order of g factors
+// into small primes. The log is solved modulo each prime power dividing the
+// order, one base-p digit at a time, and the results are combined with the
+// Chinese remainder theorem. Pollard rho, used for the larger primes, reads
+// its starting points from random, or from crypto/rand if random is nil.
+func PohligHellman(random io.Reader, grp *dh.DHGroup, h *big.Int) (*big.Int, error) {
+	analysis, err := Analyze(grp, FactorBound)
+	if err != nil {
+		return nil, err
+	}
+	if !analysis.Smooth {
+		return nil, fmt.Errorf("group is not smooth: %s", analysis)
+	}
+	n := analysis.Order
+
+	var residues, moduli []*big.Int
+	for _, f := range analysis.Factors {
+		pe := new(big.Int).Exp(f.Prime, big.NewInt(int64(f.Exp)), nil)
+
+		// Move g and h into the subgroup of order p^e.
+		cofactor := new(big.Int).Div(n, pe)
+		gi := new(big.Int).Exp(grp.G, cofactor, grp.P)
+		hi := new(big.Int).Exp(h, cofactor, grp.P)
+
+		xi, err := primePowerLog(random, grp.P, gi, hi, f)
+		if err != nil {
+			return nil, fmt.Errorf("log mod %d^%d: %v", f.Prime, f.Exp, err)
+		}
+		residues = append(residues, xi)
+		moduli = append(moduli, pe)
+	}
+
+	// The residues always combine, but only give the log if h is a power of g.
+	x, _ := dh.CRT(residues, moduli)
+	if new(big.Int).Exp(grp.G, x, grp.P).Cmp(new(big.Int).Mod(h, grp.P)) != 0 {
+		return nil, fmt.Errorf("no discrete log found for h = %d", h)
+	}
+	return x, nil
+}
+
+// primePowerLog solves g^x = h where g has order q^e, learning one base-q
+// digit of x per iteration.
+func primePowerLog(random io.Reader, p, g, h *big.Int, f Factor) (*big.Int, error) {
+	q := f.Prime
+
+	// gamma = g^(q^(e-1)) has order q
+	qe1 := new(big.Int).Exp(q, big.NewInt(int64(f.Exp-1)), nil)
+	gamma := &dh.DHGroup{P: p, G: new(big.Int).Exp(g, qe1, p)}
+	gInv := new(big.Int).ModInverse(g, p)
+
+	x := new(big.Int)
+	qk := big.NewInt(1)
+	for k := 0; k < f.Exp; k++ {
+		// hk = (g^-x * h)^(q^(e-1-k))
+		exp := new(big.Int).Exp(q, big.NewInt(int64(f.Exp-1-k)), nil)
+		hk := new(big.Int).Exp(gInv, x, p)
+		hk.Mul(hk, h).Mod(hk, p)
+		hk.Exp(hk, exp, p)
+
+		d, err := primeLog(random, gamma, hk, q)
+		if err != nil {
+			return nil, err
+		}
+		x.Add(x, new(big.Int).Mul(d, qk))
+		qk.Mul(qk, q)
+	}
+	return x, nil
+}
+
+// primeLog picks baby-step giant-step for small prime orders and Pollard rho
+// for larger ones.
+func primeLog(random io.Reader, grp *dh.DHGroup, h, q *big.Int) (*big.Int, error) {
+	if q.BitLen() <= 32 {
+		return BSGS(grp, h, q)
+	}
+	return PollardRho(random, grp, h, q)
+}
+
+// GenerateSmoothGroup creates a group of roughly the given size whose order
+// p-1 is twice a product of primes of factorBits bits each, along with a
+// generator of the whole multiplicative group. Such a group is unsafe and is
+// meant for demonstrating Pohlig-Hellman. Randomness is read from random, or
+// from crypto/rand if random is nil.
+func GenerateSmoothGroup(random io.Reader, bits, factorBits int) (*dh.DHGroup, error) {
+	if factorBits < 2 || factorBits >= bits {
+		return nil, fmt.Errorf("invalid factor size %d for %d-bit group", factorBits, bits)
+	}
+
+	p := new(big.Int)
+	var primes []*big.Int
+	for {
+		primes = primes[:0]
+		order := big.NewInt(2)
+		for order.BitLen() < bits {
+			// Shrink the last factor so p comes out close to the requested size.
+			size := factorBits
+			if remaining := bits - order.BitLen(); remaining < size {
+				size = remaining
+			}
+			if size < 2 {
+				break
+			}
+
+			r, err := randutil.Prime(random, size)
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate factor: %v", err)
+			}
+			order.Mul(order, r)
+			primes = append(primes, r)
+		}
+		p.Add(order, one)
+		if p.BitLen() == bits && p.ProbablyPrime(20) {
+			break
+		}
+	}
+	primes = append(primes, two)
+
+	// g generates the whole group if g^((p-1)/r) != 1 for each prime r.
+	pMinusOne := new(big.Int).Sub(p, one)
+	for {
+		g, err := randutil.Int(random, pMinusOne)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate generator: %v", err)
+		}
+		if g.Cmp(one) <= 0 {
+			continue
+		}
+
+		generator := true
+		for _, r := range primes {
+			exp := new(big.Int).Div(pMinusOne, r)
+			if new(big.Int).Exp(g, exp, p).Cmp(one) == 0 {
+				generator = false
+				break
+			}
+		}
+		if generator {
+			return &dh.DHGroup{P: p, G: g, Q: pMinusOne}, nil
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package dlog
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+
+	dh "github.com/jessesomerville/cryptopals_set5/diffie_hellman"
+	"github.com/jessesomerville/cryptopals_set5/randutil"
+)
+
+// MaxRhoBits is the largest prime order PollardRho is expected to handle in
+// reasonable time. The walk takes about sqrt(n) steps, each a few big.Int
+// multiplications, so 40 bits means around a million steps; 64 bits would be
+// billions.
+const MaxRhoBits = 40
+
+// rhoAttempts is how many random starting points PollardRho tries before
+// giving up.
+const rhoAttempts = 16
+
+// PollardRho finds x in [0, n) such that g^x = h mod p, where n is the prime
+// order of g. It uses Floyd's cycle finding on the walk that partitions the
+// group into three sets by the value of the element mod 3. Starting points are
+// read from random, or from crypto/rand if random is nil.
+func PollardRho(random io.Reader, grp *dh.DHGroup, h, n *big.Int) (*big.Int, error) {
+	if !n.ProbablyPrime(20) {
+		return nil, fmt.Errorf("Pollard rho needs a prime order, got %d", n)
+	}
+	if n.Cmp(big.NewInt(3)) <= 0 {
+		return BSGS(grp, h, n)
+	}
+
+	for attempt := 0; attempt < rhoAttempts; attempt++ {
+		x, err := rhoWalk(random, grp, h, n)
+		if err == nil {
+			return x, nil
+		}
+	}
+	return nil, fmt.Errorf("Pollard rho failed after %d attempts", rhoAttempts)
+}
+
+// rhoState is a point y = g^a * h^b on the walk.
+type rhoState struct {
+	y, a, b *big.Int
+}
+
+func rhoWalk(random io.Reader, grp *dh.DHGroup, h, n *big.Int) (*big.Int, error) {
+	a0, err := randutil.Int(random, n)
+	if err != nil {
+		return nil, err
+	}
+	b0, err := randutil.Int(random, n)
+	if err != nil {
+		return nil, err
+	}
+
+	y0 := new(big.Int).Exp(grp.G, a0, grp.P)
+	y0.Mul(y0, new(big.Int).Exp(h, b0, grp.P)).Mod(y0, grp.P)
+
+	tortoise := &rhoState{y: y0, a: a0, b: b0}
+	hare := &rhoState{
+		y: new(big.Int).Set(y0),
+		a: new(big.Int).Set(a0),
+		b: new(big.Int).Set(b0),
+	}
+
+	three := big.NewInt(3)
+	mod := new(big.Int)
+	step := func(s *rhoState) {
+		switch mod.Mod(s.y, three).Int64() {
+		case 0:
+			s.y.Mul(s.y, s.y).Mod(s.y, grp.P)
+			s.a.Lsh(s.a, 1).Mod(s.a, n)
+			s.b.Lsh(s.b, 1).Mod(s.b, n)
+		case 1:
+			s.y.Mul(s.y, grp.G).Mod(s.y, grp.P)
+			s.a.Add(s.a, one).Mod(s.a, n)
+		case 2:
+			s.y.Mul(s.y, h).Mod(s.y, grp.P)
+			s.b.Add(s.b, one).Mod(s.b, n)
+		}
+	}
+
+	// The walk must cycle within O(n) steps.
+	limit := new(big.Int).Lsh(n, 1)
+	for i := new(big.Int); i.Cmp(limit) < 0; i.Add(i, one) {
+		step(tortoise)
+		step(hare)
+		step(hare)
+		if tortoise.y.Cmp(hare.y) != 0 {
+			continue
+		}
+
+		// g^a1 h^b1 = g^a2 h^b2  =>  x = (a2 - a1) / (b1 - b2) mod n
+		db := new(big.Int).Sub(tortoise.b, hare.b)
+		db.Mod(db, n)
+		if db.Sign() == 0 {
+			return nil, fmt.Errorf("degenerate collision")
+		}
+		x := new(big.Int).Sub(hare.a, tortoise.a)
+		x.Mul(x, db.ModInverse(db, n)).Mod(x, n)
+
+		// A collision only gives the log if h is in the subgroup generated by g.
+		if new(big.Int).Exp(grp.G, x, grp.P).Cmp(new(big.Int).Mod(h, grp.P)) != 0 {
+			return nil, fmt.Errorf("no discrete log found for h = %d", h)
+		}
+		return x, nil
+	}
+	return nil, fmt.Errorf("walk did not cycle")
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jessesomerville/cryptopals_set5/color"
+	socketclient "github.com/jessesomerville/cryptopals_set5/socket_client"
+)
+
+// ecdhMITM runs the handshake over the given curve with a MITM that swaps
+// both public keys for its own and then reads the traffic between the peers.
+func ecdhMITM(curve string) error {
+	clientA, err := socketclient.NewDHSocketClient("ClientA", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	clientB, err := socketclient.NewDHSocketClient("ClientB", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	mitm, err := socketclient.NewMITMSocketClient("MITM", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := clientB.SetKeyAgreement(curve); err != nil {
+		return err
+	}
+
+	go mitm.Listen()    // Start MITM listener
+	go clientA.Listen() // Start Peer listener
+
+	fmt.Println("[+] Waiting 1 second to attempt to connect")
+	time.Sleep(time.Second)
+
+	// Have ClientB connect to the MITM
+	if err := clientB.Connect(mitm.Port); err != nil {
+		return err
+	}
+	defer clientB.Conn.Close()
+
+	// Have the MITM connect to ClientA
+	if err := mitm.Connect(clientA.Port); err != nil {
+		return err
+	}
+	defer mitm.Conn.Close()
+
+	if err := clientB.DoHandshake(mitm.Port); err != nil {
+		return err
+	}
+
+	// Each client ends up with a key shared with the MITM
+	if err := clientA.ComputeSessionKey(); err != nil {
+		return err
+	}
+	if err := clientB.ComputeSessionKey(); err != nil {
+		return err
+	}
+	if err := mitm.ComputeSessionKeys(); err != nil {
+		return err
+	}
+
+	fmt.Printf("[+] Finished %s handshake\n", curve)
+
+	msg := socketclient.Message{
+		Type: 4,
+		Data: []byte("Hello"),
+	}
+	if err := clientB.SendMessage(clientB.Conn, msg); err != nil {
+		return err
+	}
+
+	respMsg, err := clientB.ReadMessage(clientB.Conn)
+	if err != nil {
+		return err
+	}
+	color.Blue("[+] %s received: %s\n\n", clientB.ID, string(respMsg.Data))
+	return nil
+}
@@ -0,0 +1,117 @@
+// Package kdf implements the NIST SP 800-108 counter-mode key derivation
+// function and uses it to split a session key into independent keys.
+package kdf
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	aescbc "github.com/jessesomerville/cryptopals_set5/aes"
+)
+
+// PRF is a pseudorandom function keyed with key, such as HMAC or CMAC.
+type PRF func(key, data []byte) ([]byte, error)
+
+// HMACSHA256 is HMAC-SHA256 as a PRF. It accepts keys of any length.
+func HMACSHA256(key, data []byte) ([]byte, error) {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil), nil
+}
+
+// CMAC is AES-CMAC as a PRF. The key must be a valid AES key.
+func CMAC(key, data []byte) ([]byte, error) {
+	return aescbc.CMAC(data, key)
+}
+
+// CounterMode derives length bytes from key as described in SP 800-108
+// section 4.1, with the fixed input data encoded as
+//
+//	label || 0x00 || context || [L]_32
+//
+// where L is the output length in bits, so outputs for different labels,
+// contexts or lengths are unrelated.
+func CounterMode(prf PRF, key, label, context []byte, length int) ([]byte, error) {
+	if length < 0 || uint64(length)*8 > 0xffffffff {
+		return nil, fmt.Errorf("cannot derive %d bytes", length)
+	}
+
+	fixed := make([]byte, 0, len(label)+1+len(context)+4)
+	fixed = append(fixed, label...)
+	fixed = append(fixed, 0)
+	fixed = append(fixed, context...)
+	fixed = binary.BigEndian.AppendUint32(fixed, uint32(length*8))
+	return CounterModeFixedInput(prf, key, fixed, length)
+}
+
+// CounterModeFixedInput derives length bytes from key with caller-encoded
+// fixed input data. Each PRF output block i is computed over [i]_32 || fixed,
+// the layout the NIST CAVP KBKDF tests call a 32-bit counter before the fixed
+// data.
+func CounterModeFixedInput(prf PRF, key, fixed []byte, length int) ([]byte, error) {
+	if length < 0 || uint64(length)*8 > 0xffffffff {
+		return nil, fmt.Errorf("cannot derive %d bytes", length)
+	}
+
+	out := make([]byte, 0, length)
+	for i := uint32(1); len(out) < length; i++ {
+		block, err := prf(key, append(binary.BigEndian.AppendUint32(nil, i), fixed...))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, block...)
+	}
+	return out[:length], nil
+}
+
+// DirectionKeys protect the messages sent by one side of a session.
+type DirectionKeys struct {
+	EncKey []byte
+	MACKey []byte
+}
+
+// SessionKeys holds a separate set of keys for the messages each side sends,
+// so a message reflected back at its sender is rejected.
+type SessionKeys struct {
+	Initiator DirectionKeys
+	Responder DirectionKeys
+}
+
+// DeriveSessionKeys expands a session key into encryption and MAC keys for
+// each direction, all with different labels. The context can bind the keys
+// to the handshake they came from. A zero macLen derives no MAC keys, for
+// AEADs that do not need one.
+func DeriveSessionKeys(prf PRF, sessionKey, context []byte, encLen, macLen int) (*SessionKeys, error) {
+	derive := func(label string, length int) ([]byte, error) {
+		if length == 0 {
+			return nil, nil
+		}
+		return CounterMode(prf, sessionKey, []byte(label), context, length)
+	}
+
+	keys := &SessionKeys{}
+	var err error
+	if keys.Initiator.EncKey, err = derive("initiator encryption key", encLen); err != nil {
+		return nil, err
+	}
+	if keys.Initiator.MACKey, err = derive("initiator mac key", macLen); err != nil {
+		return nil, err
+	}
+	if keys.Responder.EncKey, err = derive("responder encryption key", encLen); err != nil {
+		return nil, err
+	}
+	if keys.Responder.MACKey, err = derive("responder mac key", macLen); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Split returns the keys a side writes with and the keys it reads with.
+func (k *SessionKeys) Split(initiator bool) (write, read DirectionKeys) {
+	if initiator {
+		return k.Initiator, k.Responder
+	}
+	return k.Responder, k.Initiator
+}
@@ -0,0 +1,127 @@
+package kdf
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+var testKey = []byte("YELLOW SUBMARINE")
+
+// kbkdfVector is one of the NIST CAVP KBKDF counter-mode known-answer tests
+// (CAVS 14.4 KDFCTR_gen.rsp) with a 32-bit counter before the fixed input
+// data. L is the output length in bits.
+type kbkdfVector struct {
+	Name  string `json:"name"`
+	PRF   string `json:"prf"`
+	L     int    `json:"l"`
+	KI    string `json:"ki"`
+	Fixed string `json:"fixed"`
+	KO    string `json:"ko"`
+}
+
+func TestCounterModeCAVP(t *testing.T) {
+	data, err := os.ReadFile("testdata/kbkdf_ctr.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vectors []kbkdfVector
+	if err := json.Unmarshal(data, &vectors); err != nil {
+		t.Fatalf("parse kbkdf_ctr.json: %v", err)
+	}
+
+	prfs := map[string]PRF{
+		"CMAC_AES128": CMAC,
+		"CMAC_AES192": CMAC,
+		"CMAC_AES256": CMAC,
+		"HMAC_SHA256": HMACSHA256,
+	}
+	for _, v := range vectors {
+		t.Run(v.Name, func(t *testing.T) {
+			prf, ok := prfs[v.PRF]
+			if !ok {
+				t.Fatalf("unknown PRF %q", v.PRF)
+			}
+			key, fixed, want := decodeHex(t, v.KI), decodeHex(t, v.Fixed), decodeHex(t, v.KO)
+
+			got, err := CounterModeFixedInput(prf, key, fixed, v.L/8)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("CounterModeFixedInput() = %x, want %x", got, want)
+			}
+		})
+	}
+}
+
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestCounterModeSeparation(t *testing.T) {
+	derive := func(label, context string, length int) []byte {
+		t.Helper()
+		out, err := CounterMode(HMACSHA256, testKey, []byte(label), []byte(context), length)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	base := derive("enc", "session 1", 32)
+	if bytes.Equal(base, derive("mac", "session 1", 32)) {
+		t.Error("different labels derived the same key")
+	}
+	if bytes.Equal(base, derive("enc", "session 2", 32)) {
+		t.Error("different contexts derived the same key")
+	}
+	// The output length is an input, so a shorter key is not a prefix of a
+	// longer one.
+	if bytes.Equal(base[:16], derive("enc", "session 1", 16)) {
+		t.Error("a 16-byte key is a prefix of the 32-byte key")
+	}
+}
+
+func TestCounterModeBadKey(t *testing.T) {
+	if _, err := CounterMode(CMAC, []byte("short"), nil, nil, 16); err == nil {
+		t.Error("CounterMode() with CMAC accepted a key that is not an AES key")
+	}
+}
+
+func TestDeriveSessionKeys(t *testing.T) {
+	keys, err := DeriveSessionKeys(CMAC, testKey, []byte("transcript hash"), 16, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	all := [][]byte{keys.Initiator.EncKey, keys.Initiator.MACKey, keys.Responder.EncKey, keys.Responder.MACKey}
+	for i := range all {
+		for j := i + 1; j < len(all); j++ {
+			if bytes.Equal(all[i], all[j]) {
+				t.Errorf("keys %d and %d are equal: %x", i, j, all[i])
+			}
+		}
+	}
+
+	write, read := keys.Split(true)
+	peerWrite, peerRead := keys.Split(false)
+	if !bytes.Equal(write.EncKey, peerRead.EncKey) || !bytes.Equal(read.MACKey, peerWrite.MACKey) {
+		t.Error("one side's write keys are not the other side's read keys")
+	}
+
+	keys, err = DeriveSessionKeys(HMACSHA256, testKey, nil, 32, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys.Initiator.EncKey) != 32 || keys.Initiator.MACKey != nil {
+		t.Errorf("DeriveSessionKeys(32, 0) = %+v", keys.Initiator)
+	}
+}
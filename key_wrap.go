@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	aescbc "github.com/jessesomerville/cryptopals_set5/aes"
+	"github.com/jessesomerville/cryptopals_set5/color"
+	socketclient "github.com/jessesomerville/cryptopals_set5/socket_client"
+)
+
+// keyWrap moves a session key between two clients wrapped under a KEK, then
+// shows that a tampered wrapped key is rejected.
+func keyWrap() error {
+	kek := make([]byte, 32)
+	if _, err := rand.Read(kek); err != nil {
+		return err
+	}
+
+	clientA, err := socketclient.NewDHSocketClient("ClientA", nil)
+	if err != nil {
+		return err
+	}
+	clientA.SessionKey = make([]byte, 16)
+	if _, err := rand.Read(clientA.SessionKey); err != nil {
+		return err
+	}
+	clientB, err := socketclient.NewDHSocketClient("ClientB", nil)
+	if err != nil {
+		return err
+	}
+
+	wrapped, err := clientA.WrapSessionKey(kek)
+	if err != nil {
+		return err
+	}
+	if err := clientB.UnwrapSessionKey(kek, wrapped); err != nil {
+		return err
+	}
+	if !bytes.Equal(clientA.SessionKey, clientB.SessionKey) {
+		return fmt.Errorf("unwrapped session key %x, want %x", clientB.SessionKey, clientA.SessionKey)
+	}
+	color.Green("[+] %s unwrapped session key %x\n", clientB.ID, clientB.SessionKey)
+
+	wrapped[len(wrapped)-1] ^= 1
+	var unwrapErr *aescbc.KeyUnwrapError
+	if err := clientB.UnwrapSessionKey(kek, wrapped); !errors.As(err, &unwrapErr) {
+		return fmt.Errorf("tampered wrapped key was not rejected: %v", err)
+	}
+	color.Red("[!] Tampered wrapped key rejected\n")
+	return nil
+}
@@ -8,5 +8,74 @@ func main() {
 	// if err := challenge35(); err != nil {
 	// 	log.Fatal(err)
 	// }
+	// if err := challenge11(); err != nil {
+	// 	log.Fatal(err)
+	// }
+	// if err := challenge12(); err != nil {
+	// 	log.Fatal(err)
+	// }
+	// if err := challenge13(); err != nil {
+	// 	log.Fatal(err)
+	// }
+	// if err := challenge14(); err != nil {
+	// 	log.Fatal(err)
+	// }
+	// if err := challenge16(); err != nil {
+	// 	log.Fatal(err)
+	// }
+	// if err := challenge17(); err != nil {
+	// 	log.Fatal(err)
+	// }
+	// if err := challenge18(); err != nil {
+	// 	log.Fatal(err)
+	// }
+	// if err := challenge19(true); err != nil {
+	// 	log.Fatal(err)
+	// }
+	// if err := challenge25(); err != nil {
+	// 	log.Fatal(err)
+	// }
+	// if err := challenge26(); err != nil {
+	// 	log.Fatal(err)
+	// }
+	// if err := challenge27(); err != nil {
+	// 	log.Fatal(err)
+	// }
+	// if err := keyWrap(); err != nil {
+	// 	log.Fatal(err)
+	// }
+	// if err := cipherSuites(socketclient.SuiteNames()...); err != nil {
+	// 	log.Fatal(err)
+	// }
+	// if err := cipherSuites("X25519-SHA256-CHACHA20POLY1305"); err != nil {
+	// 	log.Fatal(err)
+	// }
+	// if err := suiteDowngrade(true); err != nil {
+	// 	log.Fatal(err)
+	// }
 	challenge39()
+	// if err := challenge49(); err != nil {
+	// 	log.Fatal(err)
+	// }
+	// if err := challenge50(); err != nil {
+	// 	log.Fatal(err)
+	// }
+	// if err := challenge57(); err != nil {
+	// 	log.Fatal(err)
+	// }
+	// if err := challenge58(); err != nil {
+	// 	log.Fatal(err)
+	// }
+	// if err := weakGroupMITM(); err != nil {
+	// 	log.Fatal(err)
+	// }
+	// if err := ecdhMITM(dh.X25519); err != nil {
+	// 	log.Fatal(err)
+	// }
+	// if err := recordLayer(); err != nil {
+	// 	log.Fatal(err)
+	// }
+	// if err := goldenTranscript(); err != nil {
+	// 	log.Fatal(err)
+	// }
 }
@@ -0,0 +1,102 @@
+package randutil
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+func TestSeeded(t *testing.T) {
+	read := func(seed string, sizes ...int) []byte {
+		r := NewSeeded([]byte(seed))
+		var out []byte
+		for _, n := range sizes {
+			b := make([]byte, n)
+			if err := Read(r, b); err != nil {
+				t.Fatal(err)
+			}
+			out = append(out, b...)
+		}
+		return out
+	}
+
+	a := read("seed", 100)
+	if b := read("seed", 100); !bytes.Equal(a, b) {
+		t.Errorf("same seed gave different output:\n%x\n%x", a, b)
+	}
+	// Output does not depend on how it is split across reads.
+	if b := read("seed", 1, 31, 32, 36); !bytes.Equal(a, b) {
+		t.Errorf("split reads gave different output:\n%x\n%x", a, b)
+	}
+	if b := read("other seed", 100); bytes.Equal(a, b) {
+		t.Errorf("different seeds gave the same output %x", a)
+	}
+}
+
+func TestPrime(t *testing.T) {
+	for _, bits := range []int{2, 3, 8, 17, 64, 256} {
+		p, err := Prime(NewSeeded([]byte("prime")), bits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.BitLen() != bits || !p.ProbablyPrime(20) {
+			t.Errorf("Prime(%d) = %d, want a %d-bit prime", bits, p, bits)
+		}
+
+		q, err := Prime(NewSeeded([]byte("prime")), bits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Cmp(q) != 0 {
+			t.Errorf("Prime(%d) with the same seed = %d and %d", bits, p, q)
+		}
+	}
+
+	p, err := Prime(NewSeeded([]byte("prime")), 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err := Prime(NewSeeded([]byte("other prime")), 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Cmp(q) == 0 {
+		t.Errorf("Prime with different seeds gave the same prime %d", p)
+	}
+
+	if _, err := Prime(nil, 1); err == nil {
+		t.Error("Prime(1) succeeded, want error")
+	}
+}
+
+func TestDefaultReader(t *testing.T) {
+	if Reader(nil) != rand.Reader {
+		t.Error("Reader(nil) is not crypto/rand.Reader")
+	}
+	seeded := NewSeeded(nil)
+	if Reader(seeded) != seeded {
+		t.Error("Reader did not return the reader it was given")
+	}
+
+	a, b := make([]byte, 32), make([]byte, 32)
+	if err := Read(nil, a); err != nil {
+		t.Fatal(err)
+	}
+	if err := Read(nil, b); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(a, b) {
+		t.Errorf("two reads from crypto/rand gave the same bytes %x", a)
+	}
+
+	p, err := Prime(nil, 128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.BitLen() != 128 || !p.ProbablyPrime(20) {
+		t.Errorf("Prime(nil, 128) = %d, want a 128-bit prime", p)
+	}
+	if _, err := Int(nil, p); err != nil {
+		t.Errorf("Int(nil, %d): %v", p, err)
+	}
+}
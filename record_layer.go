@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jessesomerville/cryptopals_set5/color"
+	dh "github.com/jessesomerville/cryptopals_set5/diffie_hellman"
+	socketclient "github.com/jessesomerville/cryptopals_set5/socket_client"
+)
+
+// recordLayer sends a message between two clients over the AES-GCM record
+// layer, then shows that flipped bits and replayed records are rejected.
+func recordLayer() error {
+	clientA, err := socketclient.NewDHSocketClient("ClientA", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	clientB, err := socketclient.NewDHSocketClient("ClientB", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := clientB.SetKeyAgreement(dh.X25519); err != nil {
+		return err
+	}
+
+	go clientA.Listen() // Start Peer listener
+
+	fmt.Println("[+] Waiting 1 second to attempt to connect")
+	time.Sleep(time.Second)
+
+	if err := clientB.Connect(clientA.Port); err != nil {
+		return err
+	}
+	defer clientB.Conn.Close()
+
+	if err := clientB.DoHandshake(clientA.Port); err != nil {
+		return err
+	}
+	if err := clientA.ComputeSessionKey(); err != nil {
+		return err
+	}
+	if err := clientB.ComputeSessionKey(); err != nil {
+		return err
+	}
+	if err := clientA.EnableRecordLayer(false); err != nil {
+		return err
+	}
+	if err := clientB.EnableRecordLayer(true); err != nil {
+		return err
+	}
+
+	msg := socketclient.Message{
+		Type: 4,
+		Data: []byte("Hello"),
+	}
+	if err := clientB.SendMessage(clientB.Conn, msg); err != nil {
+		return err
+	}
+	respMsg, err := clientB.ReadMessage(clientB.Conn)
+	if err != nil {
+		return err
+	}
+	color.Blue("[+] %s received: %s\n\n", clientB.ID, string(respMsg.Data))
+
+	// Tamper with records sealed under the same session key.
+	context := []byte(dh.X25519)
+	sender, err := socketclient.NewRecordLayer(clientB.SessionKey, context, true)
+	if err != nil {
+		return err
+	}
+	receiver, err := socketclient.NewRecordLayer(clientB.SessionKey, context, false)
+	if err != nil {
+		return err
+	}
+
+	record, err := sender.Seal(socketclient.Message{Type: 4, Data: []byte("amount=10")})
+	if err != nil {
+		return err
+	}
+	tampered := append([]byte{}, record...)
+	tampered[len(tampered)-20] ^= 1
+	if _, err := receiver.Open(tampered); !errors.Is(err, socketclient.ErrBadRecord) {
+		return fmt.Errorf("tampered record was not rejected: %v", err)
+	}
+	color.Green("[+] Tampered record rejected\n")
+
+	if _, err := receiver.Open(record); err != nil {
+		return err
+	}
+	if _, err := receiver.Open(record); !errors.Is(err, socketclient.ErrBadRecord) {
+		return fmt.Errorf("replayed record was not rejected: %v", err)
+	}
+	color.Green("[+] Replayed record rejected\n")
+	return nil
+}
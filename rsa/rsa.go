@@ -1,8 +1,10 @@
 package rsa
 
 import (
-	"crypto/rand"
+	"io"
 	"math/big"
+
+	"github.com/jessesomerville/cryptopals_set5/randutil"
 )
 
 var (
@@ -10,8 +12,10 @@ var (
 	one  = big.NewInt(1)
 )
 
-func RandPrime() (*big.Int, error) {
-	return rand.Prime(rand.Reader, 1024)
+// RandPrime generates a 1024-bit prime from random, or from crypto/rand if
+// random is nil.
+func RandPrime(random io.Reader) (*big.Int, error) {
+	return randutil.Prime(random, 1024)
 }
 
 // func Invmod(e, et *big.Int) *big.Int {
@@ -2,7 +2,9 @@ package socketclient
 
 import (
 	"context"
+	"crypto/hmac"
 	"fmt"
+	"io"
 	"log"
 	"math/big"
 	"net"
@@ -11,6 +13,7 @@ import (
 	"github.com/jessesomerville/cryptopals_set5/color"
 	aescbc "github.com/jessesomerville/cryptopals_set5/aes"
 	dh "github.com/jessesomerville/cryptopals_set5/diffie_hellman"
+	"github.com/jessesomerville/cryptopals_set5/kdf"
 )
 
 // DHSocketClient handles socket connections on localhost and can perform DH
@@ -25,10 +28,60 @@ type DHSocketClient struct {
 	KeyPair    *dh.DHKeyPair
 	PeerPubKey *big.Int
 	SessionKey []byte
+
+	// Agreement is the key pair used in the handshake. It is the same as
+	// KeyPair unless an ECDH scheme was selected with SetKeyAgreement.
+	Agreement      dh.KeyAgreement
+	PeerPubKeyData []byte
+
+	// Records authenticates and encrypts messages once enabled with
+	// EnableRecordLayer. Until then messages are CBC encrypted under
+	// SessionKey if it is set.
+	Records *RecordLayer
+
+	// ValidatePeerGroup makes the client reject DH groups from peers that
+	// fail dh.ValidateGroup.
+	ValidatePeerGroup bool
+
+	// Rand is the source of keys and IVs. If nil, crypto/rand is used.
+	Rand io.Reader
+	// Transcript, if set, records every message sent and received on the
+	// wire, one hex-encoded line per message.
+	Transcript io.Writer
+
+	// Suites, if set, makes DoHandshake start by offering these cipher
+	// suites in order of preference. A responder chooses from Suites, or from
+	// DefaultSuites if Suites is nil.
+	Suites []string
+	// Suite is the cipher suite agreed in the handshake, if one was
+	// negotiated. DoHandshake then installs the session key itself.
+	Suite *CipherSuite
+	// RequireFinished makes a negotiated handshake end with Finished messages
+	// carrying a MAC of the handshake transcript, so that a modified
+	// handshake, such as a stripped suite offer, is detected. The initiator
+	// asks for them in its suite offer, and either side fails the handshake
+	// if the other does not agree.
+	RequireFinished bool
+	// Keys are the per-direction encryption and MAC keys derived from
+	// SessionKey when the negotiated suite uses CBC with HMAC.
+	Keys *kdf.SessionKeys
+
+	handshake  *handshakeTranscript
+	finished   bool
+	pendingKey []byte
+	initiator  bool
 }
 
-func NewDHSocketClient(id string) (*DHSocketClient, error) {
-	client := DHSocketClient{}
+// Labels for the Finished MACs sent by each side.
+const (
+	initiatorFinished = "initiator finished"
+	responderFinished = "responder finished"
+)
+
+// NewDHSocketClient creates a client listening on a free port, generating its
+// keys from random, or from crypto/rand if random is nil.
+func NewDHSocketClient(id string, random io.Reader) (*DHSocketClient, error) {
+	client := DHSocketClient{Rand: random}
 
 	port, err := getFreePort()
 	if err != nil {
@@ -36,16 +89,91 @@ func NewDHSocketClient(id string) (*DHSocketClient, error) {
 	}
 	client.Port = port
 
-	keyPair, err := dh.GenerateKeyPair(dh.GetGroup())
+	keyPair, err := dh.GenerateKeyPair(random, dh.GetGroup())
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate key pair for socket client: %v", err)
 	}
 	client.KeyPair = keyPair
+	client.Agreement = keyPair
 
 	client.ID = id
 	return &client, nil
 }
 
+// SetKeyAgreement switches the key agreement the client offers in DoHandshake
+// to the named scheme (dh.MODP, dh.X25519 or dh.P256).
+func (client *DHSocketClient) SetKeyAgreement(name string) error {
+	agreement, err := dh.GenerateKeyAgreement(client.Rand, name)
+	if err != nil {
+		return fmt.Errorf("%s - set key agreement: %v", client.ID, err)
+	}
+	if keyPair, ok := agreement.(*dh.DHKeyPair); ok {
+		client.KeyPair = keyPair
+	}
+	client.Agreement = agreement
+	return nil
+}
+
+// ComputeSessionKey derives the session key from the client's key pair and
+// the public key the peer sent during the handshake.
+func (client *DHSocketClient) ComputeSessionKey() error {
+	if client.Suite != nil {
+		sessionKey, err := client.Suite.SessionKey(client.Agreement, client.PeerPubKeyData)
+		if err != nil {
+			return fmt.Errorf("%s - compute session key: %v", client.ID, err)
+		}
+		client.SessionKey = sessionKey
+		return nil
+	}
+
+	sessionKey, err := dh.SessionKey(client.Agreement, client.PeerPubKeyData)
+	if err != nil {
+		return fmt.Errorf("%s - compute session key: %v", client.ID, err)
+	}
+	client.SessionKey = sessionKey[:16]
+	return nil
+}
+
+// EnableRecordLayer switches the client to the AES-GCM record layer keyed
+// from SessionKey. The initiator is the side that called DoHandshake. Without
+// a negotiated suite there is no transcript, so the keys are bound to the key
+// agreement name instead.
+func (client *DHSocketClient) EnableRecordLayer(initiator bool) error {
+	if client.SessionKey == nil {
+		return fmt.Errorf("%s - no session key for record layer", client.ID)
+	}
+	records, err := NewRecordLayer(client.SessionKey, []byte(client.Agreement.Name()), initiator)
+	if err != nil {
+		return fmt.Errorf("%s - %v", client.ID, err)
+	}
+	client.Records = records
+	return nil
+}
+
+// WrapSessionKey wraps SessionKey under kek with RFC 5649 key wrap so it can
+// be persisted or sent to another process.
+func (client *DHSocketClient) WrapSessionKey(kek []byte) ([]byte, error) {
+	if client.SessionKey == nil {
+		return nil, fmt.Errorf("%s - no session key to wrap", client.ID)
+	}
+	wrapped, err := aescbc.KeyWrapPad(kek, client.SessionKey)
+	if err != nil {
+		return nil, fmt.Errorf("%s - wrap session key: %v", client.ID, err)
+	}
+	return wrapped, nil
+}
+
+// UnwrapSessionKey unwraps a key produced by WrapSessionKey and installs it as
+// the client's SessionKey.
+func (client *DHSocketClient) UnwrapSessionKey(kek, wrapped []byte) error {
+	sessionKey, err := aescbc.KeyUnwrapPad(kek, wrapped)
+	if err != nil {
+		return fmt.Errorf("%s - unwrap session key: %w", client.ID, err)
+	}
+	client.SessionKey = sessionKey
+	return nil
+}
+
 func (client *DHSocketClient) Listen() (err error) {
 	l, err := net.Listen("tcp", fmt.Sprintf(":%d", client.Port))
 	if err != nil {
@@ -77,20 +205,69 @@ func (client *DHSocketClient) handleConnection(conn net.Conn) {
 		if err != nil {
 			log.Fatal(err)
 		}
-		client.KeyPair.Group = peerDHGroup
+
+		if client.Suite != nil && client.Suite.KeyAgreement != dh.MODP {
+			color.Red("[!] %s expected %s key agreement\n", client.ID, client.Suite.KeyAgreement)
+			respMsg = Message{
+				Type: 1,
+				Data: []byte("NACK"),
+			}
+			break
+		}
+		if client.ValidatePeerGroup {
+			if report := dh.ValidateGroup(peerDHGroup); !report.OK() {
+				color.Red("[!] %s rejected peer group: %s\n", client.ID, report)
+				respMsg = Message{
+					Type: 1,
+					Data: []byte("NACK"),
+				}
+				break
+			}
+		}
+		// The key pair only has to change if the peer picked a different
+		// group, such as one injected by a MITM, since the old public key
+		// would not be an element of it.
+		if !sameGroup(client.KeyPair.Group, peerDHGroup) {
+			keyPair, err := dh.GenerateKeyPair(client.Rand, peerDHGroup)
+			if err != nil {
+				log.Fatal(err)
+			}
+			client.KeyPair = keyPair
+		}
+		client.Agreement = client.KeyPair
+
+		respMsg = Message{
+			Type: 1,
+			Data: []byte("ACK"),
+		}
+	case 5: // Recieve ECDH curve name from peer
+		if client.Suite != nil && client.Suite.KeyAgreement != string(msg.Data) {
+			color.Red("[!] %s expected %s key agreement\n", client.ID, client.Suite.KeyAgreement)
+			respMsg = Message{
+				Type: 1,
+				Data: []byte("NACK"),
+			}
+			break
+		}
+		if err := client.SetKeyAgreement(string(msg.Data)); err != nil {
+			color.Red("[!] %v\n", err)
+			respMsg = Message{
+				Type: 1,
+				Data: []byte("NACK"),
+			}
+			break
+		}
 
 		respMsg = Message{
 			Type: 1,
 			Data: []byte("ACK"),
 		}
 	case 2: // Recieve pubkey from peer
-		peerPubKey := big.Int{}
-		peerPubKey.SetBytes(msg.Data)
-		client.PeerPubKey = &peerPubKey
+		client.setPeerPubKey(msg.Data)
 
 		respMsg = Message{
 			Type: 3,
-			Data: client.KeyPair.PubKey.Bytes(),
+			Data: client.Agreement.PublicKey(),
 		}
 	case 4:
 		color.Blue("[+] %s recieved: %s", client.ID, string(msg.Data))
@@ -99,13 +276,106 @@ func (client *DHSocketClient) handleConnection(conn net.Conn) {
 			Type: 2,
 			Data: []byte("World"),
 		}
+	case 6: // Recieve cipher suite offer from peer
+		respMsg = client.handleSuiteOffer(msg)
+	case 7: // Recieve Finished from peer
+		client.handleFinished(conn, msg)
+		client.handleConnection(conn)
+		return
 	default:
 		log.Fatalf("%s recieved unknown message type: %d", client.ID, msg.Type)
 	}
+
+	if client.handshake != nil {
+		client.handshake.add(msg)
+		client.handshake.add(&respMsg)
+	}
 	client.SendMessage(conn, respMsg)
+
+	// The responder has both public keys once it has replied with its own.
+	if msg.Type == 2 && client.Suite != nil {
+		sessionKey, err := client.Suite.SessionKey(client.Agreement, client.PeerPubKeyData)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if client.finished {
+			client.pendingKey = sessionKey
+		} else if err := client.installSessionKey(sessionKey, false); err != nil {
+			log.Fatal(err)
+		}
+	}
 	client.handleConnection(conn)
 }
 
+// handleSuiteOffer chooses a cipher suite from the peer's offer and starts
+// hashing the handshake transcript.
+func (client *DHSocketClient) handleSuiteOffer(msg *Message) Message {
+	preferred := client.Suites
+	if preferred == nil {
+		preferred = SuiteNames()
+	}
+
+	offered, finished := decodeOffer(msg.Data)
+	if client.RequireFinished && !finished {
+		color.Red("[!] %s - peer did not offer Finished messages\n", client.ID)
+		return Message{
+			Type: 1,
+			Data: []byte("NACK"),
+		}
+	}
+	suite, err := ChooseSuite(preferred, offered)
+	if err != nil {
+		color.Red("[!] %s - %v\n", client.ID, err)
+		return Message{
+			Type: 1,
+			Data: []byte("NACK"),
+		}
+	}
+	client.Suite = suite
+	client.finished = finished
+	client.handshake = newHandshakeTranscript()
+
+	color.Blue("[+] %s chose cipher suite %s\n", client.ID, suite.Name)
+	return Message{
+		Type: 1,
+		Data: encodeOffer([]string{suite.Name}, finished),
+	}
+}
+
+// handleFinished checks the initiator's Finished MAC against the responder's
+// own transcript and, if it matches, replies with its own Finished and starts
+// using the session key.
+func (client *DHSocketClient) handleFinished(conn net.Conn, msg *Message) {
+	if client.handshake == nil || client.pendingKey == nil {
+		log.Fatalf("%s recieved unexpected Finished message", client.ID)
+	}
+
+	want := client.handshake.finished(client.pendingKey, initiatorFinished)
+	if !hmac.Equal(msg.Data, want) {
+		color.Red("[!] %s rejected Finished: handshake transcript does not match\n", client.ID)
+		client.Suite, client.handshake, client.finished, client.pendingKey = nil, nil, false, nil
+		client.SendMessage(conn, Message{Type: 1, Data: []byte("NACK")})
+		return
+	}
+	client.handshake.add(msg)
+
+	respMsg := Message{
+		Type: 7,
+		Data: client.handshake.finished(client.pendingKey, responderFinished),
+	}
+	client.SendMessage(conn, respMsg)
+
+	if err := client.installSessionKey(client.pendingKey, false); err != nil {
+		log.Fatal(err)
+	}
+	client.pendingKey = nil
+}
+
+// sameGroup reports whether a and b have the same prime and generator.
+func sameGroup(a, b *dh.DHGroup) bool {
+	return a.P.Cmp(b.P) == 0 && a.G.Cmp(b.G) == 0
+}
+
 func (client *DHSocketClient) Connect(port int) error {
 	var d net.Dialer
 	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
@@ -120,41 +390,161 @@ func (client *DHSocketClient) Connect(port int) error {
 }
 
 func (client *DHSocketClient) DoHandshake(peerPort int) error {
-	clientKeyData, err := dh.SerializeDHGroup(client.KeyPair.Group)
+	if client.Suites != nil {
+		if err := client.negotiateSuite(); err != nil {
+			return err
+		}
+	}
+
+	initMsg := Message{Type: 5, Data: []byte(client.Agreement.Name())}
+	if client.Agreement.Name() == dh.MODP {
+		clientKeyData, err := dh.SerializeDHGroup(client.KeyPair.Group)
+		if err != nil {
+			return err
+		}
+		initMsg = Message{Type: 0, Data: clientKeyData}
+	}
+
+	respMsg, err := client.exchange(initMsg)
 	if err != nil {
 		return err
 	}
 
-	initMsg := Message{Type: 0, Data: clientKeyData}
-	if err := client.SendMessage(client.Conn, initMsg); err != nil {
-		return err
+	if respMsg.Type != 1 {
+		return fmt.Errorf("%s - peer replied to message type %d with message type %d", client.ID, initMsg.Type, respMsg.Type)
 	}
-	respMsg, err := client.ReadMessage(client.Conn)
+	if string(respMsg.Data) != "ACK" {
+		return fmt.Errorf("%s - peer rejected %s key agreement", client.ID, client.Agreement.Name())
+	}
+
+	pubKeyMsg := Message{Type: 2, Data: client.Agreement.PublicKey()}
+	respMsg, err = client.exchange(pubKeyMsg)
 	if err != nil {
 		return err
 	}
 
-	if respMsg.Type != 1 {
-		return fmt.Errorf("%s - peer replied to message type 0 with message type %d", client.ID, respMsg.Type)
+	if respMsg.Type != 3 {
+		return fmt.Errorf("%s - peer replied to message type 2 with message type %d", client.ID, respMsg.Type)
+	}
+
+	client.setPeerPubKey(respMsg.Data)
+	if client.Suite != nil {
+		return client.finishHandshake()
 	}
+	return nil
+}
 
-	pubKeyMsg := Message{Type: 2, Data: client.KeyPair.PubKey.Bytes()}
-	if err := client.SendMessage(client.Conn, pubKeyMsg); err != nil {
+// negotiateSuite offers Suites to the peer and switches to the key agreement
+// of the suite it chooses.
+func (client *DHSocketClient) negotiateSuite() error {
+	client.Suite = nil
+	client.handshake = newHandshakeTranscript()
+
+	offerMsg := Message{Type: 6, Data: encodeOffer(client.Suites, client.RequireFinished)}
+	respMsg, err := client.exchange(offerMsg)
+	if err != nil {
 		return err
 	}
-	respMsg, err = client.ReadMessage(client.Conn)
+	if respMsg.Type != 1 {
+		return fmt.Errorf("%s - peer replied to message type 6 with message type %d", client.ID, respMsg.Type)
+	}
+
+	chosen, finished := decodeOffer(respMsg.Data)
+	suite, err := ChooseSuite(chosen, client.Suites)
 	if err != nil {
+		return fmt.Errorf("%s - peer rejected cipher suites %v", client.ID, client.Suites)
+	}
+	if finished != client.RequireFinished {
+		return fmt.Errorf("%s - peer did not agree to Finished messages", client.ID)
+	}
+	client.finished = finished
+	if err := client.SetKeyAgreement(suite.KeyAgreement); err != nil {
 		return err
 	}
+	client.Suite = suite
+	return nil
+}
 
-	if respMsg.Type != 3 {
-		return fmt.Errorf("%s - peer replied to message type 2 with message type %d", client.ID, respMsg.Type)
+// finishHandshake derives the session key for the negotiated suite and, if
+// Finished messages were agreed, checks the peer saw the same handshake before
+// using it.
+func (client *DHSocketClient) finishHandshake() error {
+	sessionKey, err := client.Suite.SessionKey(client.Agreement, client.PeerPubKeyData)
+	if err != nil {
+		return fmt.Errorf("%s - compute session key: %v", client.ID, err)
 	}
 
+	if client.finished {
+		finishedMsg := Message{
+			Type: 7,
+			Data: client.handshake.finished(sessionKey, initiatorFinished),
+		}
+		if err := client.SendMessage(client.Conn, finishedMsg); err != nil {
+			return err
+		}
+		client.handshake.add(&finishedMsg)
+
+		respMsg, err := client.ReadMessage(client.Conn)
+		if err != nil {
+			return err
+		}
+		if respMsg.Type != 7 {
+			return fmt.Errorf("%s - peer rejected Finished, the handshake was modified in transit", client.ID)
+		}
+		want := client.handshake.finished(sessionKey, responderFinished)
+		if !hmac.Equal(respMsg.Data, want) {
+			return fmt.Errorf("%s - peer Finished does not match, the handshake was modified in transit", client.ID)
+		}
+	}
+	return client.installSessionKey(sessionKey, true)
+}
+
+// installSessionKey starts protecting messages with sessionKey using the
+// negotiated suite's mode. The keys are derived with the handshake transcript
+// hash as the KDF context, which covers the suite offer and choice.
+func (client *DHSocketClient) installSessionKey(sessionKey []byte, initiator bool) error {
+	context := client.handshake.sum()
+	client.SessionKey = sessionKey
+	client.handshake = nil
+	client.initiator = initiator
+
+	var err error
+	switch client.Suite.Mode {
+	case ModeCBCHMAC:
+		client.Keys, err = kdf.DeriveSessionKeys(kdf.HMACSHA256, sessionKey, context, 16, 32)
+	case ModeGCM:
+		client.Records, err = NewRecordLayer(sessionKey, context, initiator)
+	case ModeChaCha20Poly1305:
+		client.Records, err = NewChaCha20Poly1305RecordLayer(sessionKey, context, initiator)
+	}
+	if err != nil {
+		return fmt.Errorf("%s - %v", client.ID, err)
+	}
+	return nil
+}
+
+// exchange sends a handshake message and reads the reply, adding both to the
+// handshake transcript if a suite is being negotiated.
+func (client *DHSocketClient) exchange(msg Message) (*Message, error) {
+	if err := client.SendMessage(client.Conn, msg); err != nil {
+		return nil, err
+	}
+	respMsg, err := client.ReadMessage(client.Conn)
+	if err != nil {
+		return nil, err
+	}
+	if client.handshake != nil {
+		client.handshake.add(&msg)
+		client.handshake.add(respMsg)
+	}
+	return respMsg, nil
+}
+
+func (client *DHSocketClient) setPeerPubKey(data []byte) {
 	peerPubKey := big.Int{}
-	peerPubKey.SetBytes(respMsg.Data)
+	peerPubKey.SetBytes(data)
 	client.PeerPubKey = &peerPubKey
-	return nil
+	client.PeerPubKeyData = data
 }
 
 func (client *DHSocketClient) ReadMessage(conn net.Conn) (*Message, error) {
@@ -164,9 +554,23 @@ func (client *DHSocketClient) ReadMessage(conn net.Conn) (*Message, error) {
 		return nil, fmt.Errorf("%s - read message: %v", client.ID, err)
 	}
 	respBytes := resp[:readLen]
+	client.record("recv", respBytes)
+
+	if client.Records != nil {
+		msg, err := client.Records.Open(respBytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s - read message: %w", client.ID, err)
+		}
+		return msg, nil
+	}
 
 	if client.SessionKey != nil {
-		respBytes, err = aescbc.Decrypt(respBytes, client.SessionKey)
+		if client.Suite != nil && client.Suite.Mode == ModeCBCHMAC {
+			_, read := client.Keys.Split(client.initiator)
+			respBytes, err = aescbc.OpenEncryptThenMAC(respBytes, read.EncKey, read.MACKey)
+		} else {
+			respBytes, err = aescbc.Decrypt(respBytes, client.SessionKey)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -180,24 +584,43 @@ func (client *DHSocketClient) ReadMessage(conn net.Conn) (*Message, error) {
 }
 
 func (client *DHSocketClient) SendMessage(conn net.Conn, msg Message) error {
-	msgData, err := msg.Serialize()
+	var msgData []byte
+	var err error
+	if client.Records != nil {
+		msgData, err = client.Records.Seal(msg)
+	} else {
+		msgData, err = msg.Serialize()
+	}
 	if err != nil {
 		return err
 	}
 
-	if client.SessionKey != nil {
-		msgData, err = aescbc.Encrypt(msgData, client.SessionKey)
+	if client.Records == nil && client.SessionKey != nil {
+		if client.Suite != nil && client.Suite.Mode == ModeCBCHMAC {
+			write, _ := client.Keys.Split(client.initiator)
+			msgData, err = aescbc.EncryptThenMAC(client.Rand, msgData, write.EncKey, write.MACKey)
+		} else {
+			msgData, err = aescbc.Encrypt(client.Rand, msgData, client.SessionKey)
+		}
 		if err != nil {
 			return err
 		}
 	}
 
+	client.record("send", msgData)
 	if _, err := conn.Write(msgData); err != nil {
 		return fmt.Errorf("%s - send message: %v", client.ID, err)
 	}
 	return nil
 }
 
+// record writes a message to the transcript, if there is one.
+func (client *DHSocketClient) record(direction string, data []byte) {
+	if client.Transcript != nil {
+		fmt.Fprintf(client.Transcript, "%s %s %x\n", client.ID, direction, data)
+	}
+}
+
 func getFreePort() (int, error) {
 	addr, err := net.ResolveTCPAddr("tcp", "localhost:0")
 	if err != nil {
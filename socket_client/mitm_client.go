@@ -3,6 +3,7 @@ package socketclient
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"math/big"
 	"net"
@@ -12,6 +13,7 @@ import (
 	"github.com/jessesomerville/cryptopals_set5/color"
 	aescbc "github.com/jessesomerville/cryptopals_set5/aes"
 	dh "github.com/jessesomerville/cryptopals_set5/diffie_hellman"
+	"github.com/jessesomerville/cryptopals_set5/dlog"
 )
 
 type MITMSocketClient struct {
@@ -24,13 +26,48 @@ type MITMSocketClient struct {
 	KeyPair     *dh.DHKeyPair
 	PeerDHGroup *dh.DHGroup
 
+	// InjectedGroup, if set, replaces the DH group the initiator sends before
+	// it is forwarded to the peer.
+	InjectedGroup *dh.DHGroup
+	// InjectedPubKey, if set, replaces the initiator's public key before it
+	// is forwarded to the peer.
+	InjectedPubKey *big.Int
+
+	// DowngradeTo, if set, replaces the initiator's cipher suite offer with
+	// just this suite before it is forwarded to the peer.
+	DowngradeTo string
+
 	ClientAPubKey *big.Int
 	ClientBPubKey *big.Int
 	SessionKey    []byte
+
+	// Agreement is the MITM's own key pair when the peers negotiate ECDH.
+	// The MITM then swaps each side's public key for its own and holds a
+	// separate session key for each connection.
+	Agreement           dh.KeyAgreement
+	InitiatorPubKey     []byte
+	ResponderPubKey     []byte
+	InitiatorSessionKey []byte
+	ResponderSessionKey []byte
+
+	// initiatorKeys are the possible initiator session keys when the
+	// injected group does not determine it. The first message from the
+	// initiator picks one.
+	initiatorKeys [][]byte
+
+	// Record layers for the connection from the initiator and the one to the
+	// responder, set by EnableRecordLayer.
+	InitiatorRecords *RecordLayer
+	ResponderRecords *RecordLayer
+
+	// Rand is the source of keys and IVs. If nil, crypto/rand is used.
+	Rand io.Reader
 }
 
-func NewMITMSocketClient(id string) (*MITMSocketClient, error) {
-	client := MITMSocketClient{}
+// NewMITMSocketClient creates a MITM listening on a free port, generating its
+// keys from random, or from crypto/rand if random is nil.
+func NewMITMSocketClient(id string, random io.Reader) (*MITMSocketClient, error) {
+	client := MITMSocketClient{Rand: random}
 
 	port, err := getFreePort()
 	if err != nil {
@@ -38,7 +75,7 @@ func NewMITMSocketClient(id string) (*MITMSocketClient, error) {
 	}
 	client.Port = port
 
-	keyPair, err := dh.GenerateKeyPair(dh.GetGroup())
+	keyPair, err := dh.GenerateKeyPair(random, dh.GetGroup())
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate key pair for socket client: %v", err)
 	}
@@ -76,10 +113,20 @@ func (client *MITMSocketClient) handleConnection(conn net.Conn) {
 	switch msg.Type {
 	case 0: // Client initiates handshake and sends DHGroup (p, g)
 		respMsg = *client.HandleHandshakeInit(msg)
+	case 5: // Client initiates handshake with an ECDH curve
+		respMsg = *client.HandleECDHInit(msg)
 	case 2: // Client sends public key
+		if client.Agreement != nil {
+			respMsg = *client.HandleECDHPubkey(msg)
+			break
+		}
 		respMsg = *client.HandleHandshakePubkey(msg)
 	case 4: // Normal message after handshake
 		respMsg = *client.HandleNormalMessage(msg)
+	case 6: // Client offers cipher suites
+		respMsg = *client.HandleSuiteOffer(msg)
+	case 7: // Client sends Finished
+		respMsg = *client.HandleFinished(msg)
 	default:
 		log.Fatalf("%s recieved unknown message type: %d", client.ID, msg.Type)
 	}
@@ -95,7 +142,13 @@ func (client *MITMSocketClient) HandleHandshakeInit(msg *Message) *Message {
 	}
 	client.PeerDHGroup = clientAGroup
 
-	forwardMsgData, err := dh.SerializeDHGroup(clientAGroup)
+	forwardGroup := clientAGroup
+	if client.InjectedGroup != nil {
+		color.Red("[+] MITM injecting DH group")
+		forwardGroup = client.InjectedGroup
+	}
+
+	forwardMsgData, err := dh.SerializeDHGroup(forwardGroup)
 	if err != nil {
 		color.Red("[!] MITM failed to serialize injected DH Group")
 		os.Exit(1)
@@ -124,7 +177,13 @@ func (client *MITMSocketClient) HandleHandshakePubkey(msg *Message) *Message {
 	clientAPubKey.SetBytes(msg.Data)
 	client.ClientAPubKey = &clientAPubKey
 
-	if err := client.SendMessage(client.Conn, *msg); err != nil {
+	forwardMsg := *msg
+	if client.InjectedPubKey != nil {
+		color.Red("[+] MITM injecting initiator public key")
+		forwardMsg.Data = client.InjectedPubKey.Bytes()
+	}
+
+	if err := client.SendMessage(client.Conn, forwardMsg); err != nil {
 		color.Red("[!] MITM failed to forward pubkey message")
 		os.Exit(1)
 	}
@@ -134,11 +193,149 @@ func (client *MITMSocketClient) HandleHandshakePubkey(msg *Message) *Message {
 		os.Exit(1)
 	}
 	clientBPubKey := big.Int{}
-	clientBPubKey.SetBytes(msg.Data)
+	clientBPubKey.SetBytes(respMsg.Data)
 	client.ClientBPubKey = &clientBPubKey
 	return respMsg
 }
 
+// HandleECDHInit generates a key pair for the MITM on the requested curve and
+// forwards the request to the peer.
+func (client *MITMSocketClient) HandleECDHInit(msg *Message) *Message {
+	color.Red("[+] MITM recieved %s handshake initiation", string(msg.Data))
+	agreement, err := dh.GenerateKeyAgreement(client.Rand, string(msg.Data))
+	if err != nil {
+		color.Red("[!] MITM failed to generate key pair: %v", err)
+		os.Exit(1)
+	}
+	client.Agreement = agreement
+
+	if err := client.SendMessage(client.Conn, *msg); err != nil {
+		color.Red("[!] MITM failed to forward handshake message")
+		os.Exit(1)
+	}
+	respMsg, err := client.ReadMessage(client.Conn)
+	if err != nil {
+		color.Red("[!] MITM failed to read handshake response")
+		os.Exit(1)
+	}
+	return respMsg
+}
+
+// HandleECDHPubkey replaces the public key in both directions with the MITM's
+// own, so each side ends up sharing a key with the MITM instead of its peer.
+func (client *MITMSocketClient) HandleECDHPubkey(msg *Message) *Message {
+	client.InitiatorPubKey = msg.Data
+
+	forwardMsg := Message{Type: 2, Data: client.Agreement.PublicKey()}
+	if err := client.SendMessage(client.Conn, forwardMsg); err != nil {
+		color.Red("[!] MITM failed to forward pubkey message")
+		os.Exit(1)
+	}
+	respMsg, err := client.ReadMessage(client.Conn)
+	if err != nil {
+		color.Red("[!] MITM failed to read pubkey response")
+		os.Exit(1)
+	}
+	client.ResponderPubKey = respMsg.Data
+
+	color.Red("[+] MITM substituted its own %s public key", client.Agreement.Name())
+	return &Message{Type: 3, Data: client.Agreement.PublicKey()}
+}
+
+// ComputeSessionKeys derives the session keys the MITM shares with each side
+// after an ECDH handshake.
+func (client *MITMSocketClient) ComputeSessionKeys() error {
+	if client.Agreement == nil {
+		return fmt.Errorf("%s - no ECDH handshake to compute keys from", client.ID)
+	}
+
+	initiatorKey, err := dh.SessionKey(client.Agreement, client.InitiatorPubKey)
+	if err != nil {
+		return fmt.Errorf("%s - compute initiator session key: %v", client.ID, err)
+	}
+	responderKey, err := dh.SessionKey(client.Agreement, client.ResponderPubKey)
+	if err != nil {
+		return fmt.Errorf("%s - compute responder session key: %v", client.ID, err)
+	}
+	client.InitiatorSessionKey = initiatorKey[:16]
+	client.ResponderSessionKey = responderKey[:16]
+	return nil
+}
+
+// EnableRecordLayer switches both connections to the AES-GCM record layer,
+// keyed with the session key the MITM shares with each side.
+func (client *MITMSocketClient) EnableRecordLayer() error {
+	initiatorKey, responderKey := client.InitiatorSessionKey, client.ResponderSessionKey
+	if initiatorKey == nil {
+		initiatorKey = client.SessionKey
+	}
+	if responderKey == nil {
+		responderKey = client.SessionKey
+	}
+	if initiatorKey == nil || responderKey == nil {
+		return fmt.Errorf("%s - no session keys for record layer", client.ID)
+	}
+
+	// The peers bind their keys to the key agreement name, as in
+	// DHSocketClient.EnableRecordLayer.
+	context := []byte(dh.MODP)
+	if client.Agreement != nil {
+		context = []byte(client.Agreement.Name())
+	}
+
+	// The MITM plays the responder towards the initiator and the initiator
+	// towards the responder.
+	initiatorRecords, err := NewRecordLayer(initiatorKey, context, false)
+	if err != nil {
+		return fmt.Errorf("%s - %v", client.ID, err)
+	}
+	responderRecords, err := NewRecordLayer(responderKey, context, true)
+	if err != nil {
+		return fmt.Errorf("%s - %v", client.ID, err)
+	}
+	client.InitiatorRecords = initiatorRecords
+	client.ResponderRecords = responderRecords
+	return nil
+}
+
+// HandleSuiteOffer strips the initiator's cipher suite offer down to
+// DowngradeTo, if set, and forwards it to the peer.
+func (client *MITMSocketClient) HandleSuiteOffer(msg *Message) *Message {
+	color.Red("[+] MITM recieved cipher suite offer: %s", string(msg.Data))
+	forwardMsg := *msg
+	if client.DowngradeTo != "" {
+		color.Red("[+] MITM downgrading offer to %s", client.DowngradeTo)
+		_, finished := decodeOffer(msg.Data)
+		forwardMsg.Data = encodeOffer([]string{client.DowngradeTo}, finished)
+	}
+
+	if err := client.SendMessage(client.Conn, forwardMsg); err != nil {
+		color.Red("[!] MITM failed to forward suite offer")
+		os.Exit(1)
+	}
+	respMsg, err := client.ReadMessage(client.Conn)
+	if err != nil {
+		color.Red("[!] MITM failed to read suite offer response")
+		os.Exit(1)
+	}
+	return respMsg
+}
+
+// HandleFinished forwards a Finished message unchanged. The MITM cannot forge
+// one without the session key.
+func (client *MITMSocketClient) HandleFinished(msg *Message) *Message {
+	if err := client.SendMessage(client.Conn, *msg); err != nil {
+		color.Red("[!] MITM failed to forward Finished message")
+		os.Exit(1)
+	}
+	respMsg, err := client.ReadMessage(client.Conn)
+	if err != nil {
+		color.Red("[!] MITM failed to read Finished response")
+		os.Exit(1)
+	}
+	return respMsg
+}
+
 func (client *MITMSocketClient) HandleNormalMessage(msg *Message) *Message {
 	color.Red("[+] %s recieved: %s", client.ID, string(msg.Data))
 	if err := client.SendMessage(client.Conn, *msg); err != nil {
@@ -154,6 +351,63 @@ func (client *MITMSocketClient) HandleNormalMessage(msg *Message) *Message {
 	return respMsg
 }
 
+// RecoverPeerKey solves for the private key of the peer that accepted the
+// injected group, which only works if the group order is smooth.
+func (client *MITMSocketClient) RecoverPeerKey() (*dh.DHKeyPair, error) {
+	if client.InjectedGroup == nil || client.ClientBPubKey == nil {
+		return nil, fmt.Errorf("%s - no injected group or peer public key", client.ID)
+	}
+
+	privKey, err := dlog.PohligHellman(client.Rand, client.InjectedGroup, client.ClientBPubKey)
+	if err != nil {
+		return nil, fmt.Errorf("%s - recover peer key: %v", client.ID, err)
+	}
+
+	keyPair := dh.NewKeyPair(client.InjectedGroup, privKey)
+	if keyPair.PubKey.Cmp(client.ClientBPubKey) != 0 {
+		return nil, fmt.Errorf("%s - recovered key does not match peer public key", client.ID)
+	}
+	return keyPair, nil
+}
+
+// ComputeInjectedSessionKeys computes both session keys after injecting a
+// generator g of 1, p or p-1 into the group and g as the initiator's public
+// key. The responder's secret g^b is then its own public key B, and the
+// initiator's is B^a, which is B unless B is p-1 and a is even.
+func (client *MITMSocketClient) ComputeInjectedSessionKeys() error {
+	if client.InjectedGroup == nil || client.ClientBPubKey == nil {
+		return fmt.Errorf("%s - no injected group or peer public key", client.ID)
+	}
+	grp := client.InjectedGroup
+	client.ResponderSessionKey = dh.DeriveKey(grp, client.ClientBPubKey)[:16]
+	client.InitiatorSessionKey = client.ResponderSessionKey
+
+	pMinus1 := new(big.Int).Sub(grp.P, big.NewInt(1))
+	if client.ClientBPubKey.Cmp(pMinus1) == 0 {
+		client.initiatorKeys = [][]byte{
+			client.ResponderSessionKey,
+			dh.DeriveKey(grp, big.NewInt(1))[:16],
+		}
+	}
+	return nil
+}
+
+// resolveInitiatorKey picks the candidate initiator key that decrypts ct into
+// a valid message.
+func (client *MITMSocketClient) resolveInitiatorKey(ct []byte) {
+	for _, key := range client.initiatorKeys {
+		pt, err := aescbc.Decrypt(ct, key)
+		if err != nil {
+			continue
+		}
+		if _, err := DeserializeMessage(pt); err == nil {
+			client.InitiatorSessionKey = key
+			break
+		}
+	}
+	client.initiatorKeys = nil
+}
+
 func (client *MITMSocketClient) Connect(port int) error {
 	var d net.Dialer
 	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
@@ -175,8 +429,19 @@ func (client *MITMSocketClient) ReadMessage(conn net.Conn) (*Message, error) {
 	}
 	respBytes := resp[:readLen]
 
-	if client.SessionKey != nil {
-		respBytes, err = aescbc.Decrypt(respBytes, client.SessionKey)
+	if records := client.recordsFor(conn); records != nil {
+		msg, err := records.Open(respBytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s - read message: %w", client.ID, err)
+		}
+		return msg, nil
+	}
+
+	if conn != client.Conn && client.initiatorKeys != nil {
+		client.resolveInitiatorKey(respBytes)
+	}
+	if sessionKey := client.sessionKeyFor(conn); sessionKey != nil {
+		respBytes, err = aescbc.Decrypt(respBytes, sessionKey)
 		if err != nil {
 			return nil, err
 		}
@@ -190,13 +455,21 @@ func (client *MITMSocketClient) ReadMessage(conn net.Conn) (*Message, error) {
 }
 
 func (client *MITMSocketClient) SendMessage(conn net.Conn, msg Message) error {
-	msgData, err := msg.Serialize()
+	records := client.recordsFor(conn)
+
+	var msgData []byte
+	var err error
+	if records != nil {
+		msgData, err = records.Seal(msg)
+	} else {
+		msgData, err = msg.Serialize()
+	}
 	if err != nil {
 		return err
 	}
 
-	if client.SessionKey != nil {
-		msgData, err = aescbc.Encrypt(msgData, client.SessionKey)
+	if sessionKey := client.sessionKeyFor(conn); records == nil && sessionKey != nil {
+		msgData, err = aescbc.Encrypt(client.Rand, msgData, sessionKey)
 		if err != nil {
 			return err
 		}
@@ -207,3 +480,24 @@ func (client *MITMSocketClient) SendMessage(conn net.Conn, msg Message) error {
 	}
 	return nil
 }
+
+// sessionKeyFor returns the key for traffic on conn. Once ECDH keys have been
+// computed, the connection to the responder and the one from the initiator
+// use different keys.
+func (client *MITMSocketClient) sessionKeyFor(conn net.Conn) []byte {
+	if conn == client.Conn && client.ResponderSessionKey != nil {
+		return client.ResponderSessionKey
+	}
+	if conn != client.Conn && client.InitiatorSessionKey != nil {
+		return client.InitiatorSessionKey
+	}
+	return client.SessionKey
+}
+
+// recordsFor returns the record layer for traffic on conn, if enabled.
+func (client *MITMSocketClient) recordsFor(conn net.Conn) *RecordLayer {
+	if conn == client.Conn {
+		return client.ResponderRecords
+	}
+	return client.InitiatorRecords
+}
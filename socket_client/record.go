@@ -0,0 +1,119 @@
+package socketclient
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	aescbc "github.com/jessesomerville/cryptopals_set5/aes"
+	"github.com/jessesomerville/cryptopals_set5/chacha20poly1305"
+	"github.com/jessesomerville/cryptopals_set5/kdf"
+)
+
+// ErrBadRecord is returned when a record fails authentication, which happens
+// if it was tampered with, replayed or reordered.
+var ErrBadRecord = errors.New("record authentication failed")
+
+// recordHeaderLen is the length of the message type prefixed to each record.
+const recordHeaderLen = 4
+
+// RecordLayer seals messages with an AEAD, AES-GCM unless ChaCha20-Poly1305
+// was chosen, once a session key has been agreed.
+// Each direction has its own key and sequence number. The nonce is the
+// sequence number, so it never repeats under a key, and the additional data
+// binds the message type and sequence number to the ciphertext.
+type RecordLayer struct {
+	send cipher.AEAD
+	recv cipher.AEAD
+
+	sendSeq uint64
+	recvSeq uint64
+}
+
+// NewRecordLayer derives the per-direction keys from the session key with the
+// SP 800-108 KDF in package kdf, using context to bind them to the handshake
+// that produced the key. The side that initiated the handshake writes with the
+// initiator key and reads with the responder key, and the other side does the
+// opposite.
+func NewRecordLayer(sessionKey, context []byte, initiator bool) (*RecordLayer, error) {
+	return newRecordLayer(sessionKey, context, initiator, aescbc.NewGCM, 16)
+}
+
+// NewChaCha20Poly1305RecordLayer is NewRecordLayer with the RFC 8439
+// ChaCha20-Poly1305 AEAD, keyed with 32-byte keys and using the same 12-byte
+// nonce derived from the sequence number.
+func NewChaCha20Poly1305RecordLayer(sessionKey, context []byte, initiator bool) (*RecordLayer, error) {
+	return newRecordLayer(sessionKey, context, initiator, chacha20poly1305.New, chacha20poly1305.KeySize)
+}
+
+func newRecordLayer(sessionKey, context []byte, initiator bool, newAEAD func(key []byte) (cipher.AEAD, error), keyLen int) (*RecordLayer, error) {
+	keys, err := kdf.DeriveSessionKeys(kdf.HMACSHA256, sessionKey, context, keyLen, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create record layer: %v", err)
+	}
+	write, read := keys.Split(initiator)
+
+	send, err := newAEAD(write.EncKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create record layer: %v", err)
+	}
+	recv, err := newAEAD(read.EncKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create record layer: %v", err)
+	}
+	return &RecordLayer{send: send, recv: recv}, nil
+}
+
+// Seal encrypts and authenticates the message as the next outgoing record.
+func (r *RecordLayer) Seal(msg Message) ([]byte, error) {
+	msgData, err := msg.Serialize()
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, recordHeaderLen)
+	binary.BigEndian.PutUint32(header, uint32(msg.Type))
+
+	nonce, ad := recordNonce(r.send, r.sendSeq), recordAD(header, r.sendSeq)
+	r.sendSeq++
+	return r.send.Seal(header, nonce, msgData, ad), nil
+}
+
+// Open authenticates and decrypts the next incoming record.
+func (r *RecordLayer) Open(record []byte) (*Message, error) {
+	if len(record) < recordHeaderLen {
+		return nil, fmt.Errorf("%w: record of length %d is too short", ErrBadRecord, len(record))
+	}
+	header := record[:recordHeaderLen]
+
+	nonce, ad := recordNonce(r.recv, r.recvSeq), recordAD(header, r.recvSeq)
+	msgData, err := r.recv.Open(nil, nonce, record[recordHeaderLen:], ad)
+	if err != nil {
+		return nil, fmt.Errorf("%w: sequence number %d", ErrBadRecord, r.recvSeq)
+	}
+	r.recvSeq++
+
+	msg, err := DeserializeMessage(msgData)
+	if err != nil {
+		return nil, err
+	}
+	if msg.Type != int(binary.BigEndian.Uint32(header)) {
+		return nil, fmt.Errorf("%w: message type does not match record header", ErrBadRecord)
+	}
+	return msg, nil
+}
+
+// recordNonce encodes the sequence number in the last 8 bytes of the nonce.
+func recordNonce(aead cipher.AEAD, seq uint64) []byte {
+	nonce := make([]byte, aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], seq)
+	return nonce
+}
+
+func recordAD(header []byte, seq uint64) []byte {
+	ad := make([]byte, len(header)+8)
+	copy(ad, header)
+	binary.BigEndian.PutUint64(ad[len(header):], seq)
+	return ad
+}
@@ -0,0 +1,169 @@
+package socketclient
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"strings"
+
+	dh "github.com/jessesomerville/cryptopals_set5/diffie_hellman"
+)
+
+// Hashes used to derive session keys from the shared secret.
+const (
+	SHA1   = "sha1"
+	SHA256 = "sha256"
+)
+
+// Symmetric modes used to protect messages once the handshake completes.
+const (
+	// ModeCBC encrypts messages with AES-128-CBC and no MAC.
+	ModeCBC = "cbc"
+	// ModeCBCHMAC encrypts messages with AES-128-CBC and HMAC-SHA256.
+	ModeCBCHMAC = "cbc-hmac"
+	// ModeGCM sends messages over the AES-GCM record layer.
+	ModeGCM = "gcm"
+	// ModeChaCha20Poly1305 sends messages over the ChaCha20-Poly1305 (RFC 8439)
+	// record layer.
+	ModeChaCha20Poly1305 = "chacha20-poly1305"
+)
+
+// CipherSuite is a key agreement scheme, a hash to derive the session key with
+// and a symmetric mode, agreed on at the start of the handshake.
+type CipherSuite struct {
+	Name         string
+	KeyAgreement string
+	Hash         string
+	Mode         string
+}
+
+// DefaultSuites lists the supported suites from strongest to weakest. The
+// last one is the handshake the clients used before suites were negotiated.
+var DefaultSuites = []CipherSuite{
+	{"X25519-SHA256-GCM", dh.X25519, SHA256, ModeGCM},
+	{"X25519-SHA256-CHACHA20POLY1305", dh.X25519, SHA256, ModeChaCha20Poly1305},
+	{"P256-SHA256-GCM", dh.P256, SHA256, ModeGCM},
+	{"MODP-SHA256-GCM", dh.MODP, SHA256, ModeGCM},
+	{"MODP-SHA256-CHACHA20POLY1305", dh.MODP, SHA256, ModeChaCha20Poly1305},
+	{"X25519-SHA256-CBCHMAC", dh.X25519, SHA256, ModeCBCHMAC},
+	{"MODP-SHA256-CBCHMAC", dh.MODP, SHA256, ModeCBCHMAC},
+	{"MODP-SHA1-CBC", dh.MODP, SHA1, ModeCBC},
+}
+
+// SuiteNames returns the names of DefaultSuites in order of preference.
+func SuiteNames() []string {
+	names := make([]string, len(DefaultSuites))
+	for i, suite := range DefaultSuites {
+		names[i] = suite.Name
+	}
+	return names
+}
+
+// SuiteByName returns a copy of the named suite from DefaultSuites, so callers
+// cannot modify the table through it.
+func SuiteByName(name string) (*CipherSuite, error) {
+	for i := range DefaultSuites {
+		if DefaultSuites[i].Name == name {
+			s := DefaultSuites[i]
+			return &s, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown cipher suite %q", name)
+}
+
+// ChooseSuite picks the first suite in preferred that the peer offered, so the
+// responder's preference wins.
+func ChooseSuite(preferred, offered []string) (*CipherSuite, error) {
+	for _, name := range preferred {
+		for _, offer := range offered {
+			if name == offer {
+				return SuiteByName(name)
+			}
+		}
+	}
+	return nil, fmt.Errorf("no common cipher suite in offer %v", offered)
+}
+
+// finishedSignal is listed after the suite names in an offer or choice when
+// the handshake ends with Finished messages, like a TLS signalling cipher
+// suite value.
+const finishedSignal = "FINISHED"
+
+// encodeOffer and decodeOffer convert a list of suite names, and whether
+// Finished messages are used, to and from the data of a suite offer or choice
+// message.
+func encodeOffer(names []string, finished bool) []byte {
+	if finished {
+		names = append(names[:len(names):len(names)], finishedSignal)
+	}
+	return []byte(strings.Join(names, ","))
+}
+
+func decodeOffer(data []byte) (names []string, finished bool) {
+	for _, name := range strings.Split(string(data), ",") {
+		if name == finishedSignal {
+			finished = true
+			continue
+		}
+		names = append(names, name)
+	}
+	return names, finished
+}
+
+// SessionKey hashes the shared secret with the suite's hash and truncates it
+// to an AES-128 key. For MODP-SHA1-CBC this matches dh.SessionKey.
+func (suite *CipherSuite) SessionKey(agreement dh.KeyAgreement, peerPubKey []byte) ([]byte, error) {
+	secret, err := agreement.SharedSecret(peerPubKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var h hash.Hash
+	switch suite.Hash {
+	case SHA1:
+		h = sha1.New()
+	case SHA256:
+		h = sha256.New()
+	default:
+		return nil, fmt.Errorf("unknown hash %q", suite.Hash)
+	}
+	h.Write(secret)
+	return h.Sum(nil)[:16], nil
+}
+
+// handshakeTranscript hashes every handshake message in the order it was sent
+// or received. Both sides end up with the same hash unless something on the
+// path changed a message.
+type handshakeTranscript struct {
+	h hash.Hash
+}
+
+func newHandshakeTranscript() *handshakeTranscript {
+	return &handshakeTranscript{h: sha256.New()}
+}
+
+// sum returns the hash of the messages added so far. It is the KDF context for
+// the session keys, so both sides must have added the same messages.
+func (t *handshakeTranscript) sum() []byte {
+	return t.h.Sum(nil)
+}
+
+func (t *handshakeTranscript) add(msg *Message) {
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header, uint32(msg.Type))
+	binary.BigEndian.PutUint32(header[4:], uint32(len(msg.Data)))
+	t.h.Write(header)
+	t.h.Write(msg.Data)
+}
+
+// finished computes the MAC for a Finished message. The label keeps the MACs
+// of the two sides distinct, so one cannot be reflected back as the other.
+func (t *handshakeTranscript) finished(sessionKey []byte, label string) []byte {
+	mac := hmac.New(sha256.New, sessionKey)
+	mac.Write([]byte(label))
+	mac.Write(t.sum())
+	return mac.Sum(nil)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jessesomerville/cryptopals_set5/color"
+	dh "github.com/jessesomerville/cryptopals_set5/diffie_hellman"
+	"github.com/jessesomerville/cryptopals_set5/randutil"
+	socketclient "github.com/jessesomerville/cryptopals_set5/socket_client"
+)
+
+// goldenTranscriptFile holds the expected output of recordHandshake. It is
+// checked by TestGoldenTranscript and regenerated with go test -update.
+const goldenTranscriptFile = "testdata/transcript.golden"
+
+// goldenTranscript runs a handshake with seeded randomness and checks that it
+// puts exactly the bytes in goldenTranscriptFile on the wire.
+func goldenTranscript() error {
+	want, err := os.ReadFile(goldenTranscriptFile)
+	if err != nil {
+		return err
+	}
+	got, err := recordHandshake()
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(got, want) {
+		return fmt.Errorf("transcript differs from %s:\n%s", goldenTranscriptFile, got)
+	}
+	fmt.Printf("%s", got)
+	color.Green("[+] Transcript matches %s\n", goldenTranscriptFile)
+	return nil
+}
+
+// recordHandshake performs an X25519 handshake and exchanges one encrypted
+// message between two seeded clients, returning everything they sent and
+// received.
+func recordHandshake() ([]byte, error) {
+	clientA, err := socketclient.NewDHSocketClient("ClientA", randutil.NewSeeded([]byte("ClientA")))
+	if err != nil {
+		return nil, err
+	}
+	clientB, err := socketclient.NewDHSocketClient("ClientB", randutil.NewSeeded([]byte("ClientB")))
+	if err != nil {
+		return nil, err
+	}
+	if err := clientB.SetKeyAgreement(dh.X25519); err != nil {
+		return nil, err
+	}
+
+	// Keep each side's transcript separate since ClientA writes to its own
+	// from the listener goroutine.
+	var transcriptA, transcriptB bytes.Buffer
+	clientA.Transcript = &transcriptA
+	clientB.Transcript = &transcriptB
+
+	go clientA.Listen() // Start Peer listener
+	time.Sleep(100 * time.Millisecond)
+
+	// The connection is left open, ClientA treats a closed connection as
+	// fatal.
+	if err := clientB.Connect(clientA.Port); err != nil {
+		return nil, err
+	}
+	if err := clientB.DoHandshake(clientA.Port); err != nil {
+		return nil, err
+	}
+	if err := clientA.ComputeSessionKey(); err != nil {
+		return nil, err
+	}
+	if err := clientB.ComputeSessionKey(); err != nil {
+		return nil, err
+	}
+
+	msg := socketclient.Message{
+		Type: 4,
+		Data: []byte("Hello"),
+	}
+	if err := clientB.SendMessage(clientB.Conn, msg); err != nil {
+		return nil, err
+	}
+	if _, err := clientB.ReadMessage(clientB.Conn); err != nil {
+		return nil, err
+	}
+
+	return append(transcriptB.Bytes(), transcriptA.Bytes()...), nil
+}
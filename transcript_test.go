@@ -0,0 +1,30 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden transcript")
+
+func TestGoldenTranscript(t *testing.T) {
+	got, err := recordHandshake()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *update {
+		if err := os.WriteFile(goldenTranscriptFile, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(goldenTranscriptFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("transcript does not match %s\ngot:\n%s\nwant:\n%s", goldenTranscriptFile, got, want)
+	}
+}
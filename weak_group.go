@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jessesomerville/cryptopals_set5/color"
+	dh "github.com/jessesomerville/cryptopals_set5/diffie_hellman"
+	"github.com/jessesomerville/cryptopals_set5/dlog"
+	socketclient "github.com/jessesomerville/cryptopals_set5/socket_client"
+)
+
+// weakGroupMITM has the MITM swap the initiator's DH group for one with a
+// smooth order. The peer accepts it without validation, and the MITM uses
+// Pohlig-Hellman to recover the peer's private key from its public key.
+func weakGroupMITM() error {
+	weakGroup, err := dlog.GenerateSmoothGroup(nil, 1024, 32)
+	if err != nil {
+		return err
+	}
+	analysis, err := dlog.Analyze(weakGroup, dlog.FactorBound)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("[+] Injected group: %s\n", analysis)
+	fmt.Printf("[+] Validation: %s\n", dh.ValidateGroup(weakGroup))
+
+	clientA, err := socketclient.NewDHSocketClient("ClientA", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	clientB, err := socketclient.NewDHSocketClient("ClientB", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	mitm, err := socketclient.NewMITMSocketClient("MITM", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	mitm.InjectedGroup = weakGroup
+
+	go mitm.Listen()    // Start MITM listener
+	go clientA.Listen() // Start Peer listener
+
+	fmt.Println("[+] Waiting 1 second to attempt to connect")
+	time.Sleep(time.Second)
+
+	// Have ClientB connect to the MITM
+	if err := clientB.Connect(mitm.Port); err != nil {
+		return err
+	}
+	defer clientB.Conn.Close()
+
+	// Have the MITM connect to ClientA
+	if err := mitm.Connect(clientA.Port); err != nil {
+		return err
+	}
+	defer mitm.Conn.Close()
+
+	if err := clientB.DoHandshake(mitm.Port); err != nil {
+		return err
+	}
+	fmt.Printf("[+] Finished handshake\n")
+
+	keyPair, err := mitm.RecoverPeerKey()
+	if err != nil {
+		return err
+	}
+	if keyPair.PubKey.Cmp(clientA.KeyPair.PubKey) != 0 {
+		return fmt.Errorf("recovered key pair does not belong to %s", clientA.ID)
+	}
+	color.Green("[+] MITM recovered %s's private key\n", clientA.ID)
+	return nil
+}